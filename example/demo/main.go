@@ -22,6 +22,8 @@ import (
 
 	"github.com/LingByte/LingConvert/media/ffmpeg"
 	"github.com/LingByte/LingConvert/media/ffprobe"
+	"github.com/LingByte/LingConvert/media/preset"
+	"github.com/LingByte/LingConvert/media/queue"
 	"github.com/gin-gonic/gin"
 )
 
@@ -92,7 +94,7 @@ type sseEvent struct {
 
 type FFJob struct {
 	ID        string
-	Status    string // created/running/done/error
+	Status    string // created/queued/running/done/error
 	CreatedAt time.Time
 
 	InputPath    string
@@ -195,9 +197,22 @@ func main() {
 	ffTool := ffmpeg.NewDefaultFFmpeg()
 	// ffmpeg 默认不建议死超时；如需限制可设置 ffTool.Timeout = 10*time.Minute 等
 	// ffTool.Timeout = 0
+	// 用 ffprobe 探测输入时长，进度里就有 Percent / ETA
+	ffTool.Prober = probeTool
 
 	jobs := NewJobStore()
 
+	// 所有 ffmpeg 任务走同一个队列，避免突发上传时起太多进程
+	ffQueue := queue.New(ffTool, queue.Options{Workers: 2})
+
+	// 输出配置：内置 preset，再加上 PRESET_DIR 目录里的 YAML/JSON
+	presets := preset.Builtin()
+	if dir := os.Getenv("PRESET_DIR"); dir != "" {
+		if err := presets.LoadDir(dir); err != nil {
+			log.Fatalf("load presets: %v", err)
+		}
+	}
+
 	r.GET("/", func(c *gin.Context) {
 		c.HTML(http.StatusOK, "index.html", PageData{})
 	})
@@ -309,32 +324,27 @@ func main() {
 		}
 
 		action := strings.TrimSpace(c.PostForm("action"))
-		outName := strings.TrimSpace(c.PostForm("out_name"))
-		crf := parseInt(c.PostForm("crf"), 23)
-		preset := strings.TrimSpace(c.PostForm("preset"))
-		if preset == "" {
-			preset = "medium"
+		if action == "" {
+			action = "transcode"
 		}
-		abitrate := strings.TrimSpace(c.PostForm("abitrate"))
-		if abitrate == "" {
-			abitrate = "128k"
+		pr, ok := presets.Get(action)
+		if !ok {
+			if cleanup != nil {
+				cleanup()
+			}
+			c.HTML(http.StatusBadRequest, "index.html", PageData{OK: false, Error: "未知操作: " + action})
+			return
 		}
-		atSec, _ := strconv.ParseFloat(strings.TrimSpace(c.PostForm("at")), 64)
-		if atSec < 0 {
-			atSec = 0
+		// 表单字段名和 preset 参数名一致，没填的用 preset 默认值
+		params := map[string]any{}
+		for _, pa := range pr.Params {
+			if v := strings.TrimSpace(c.PostForm(pa.Name)); v != "" {
+				params[pa.Name] = v
+			}
 		}
-
+		outName := strings.TrimSpace(c.PostForm("out_name"))
 		if outName == "" {
-			switch action {
-			case "extract_aac":
-				outName = "out.aac"
-			case "snapshot":
-				outName = "shot.jpg"
-			case "remux":
-				outName = "out.mp4"
-			default:
-				outName = "out.mp4"
-			}
+			outName = "out" + pr.Extension
 		}
 
 		ext := filepath.Ext(outName)
@@ -364,7 +374,31 @@ func main() {
 		}
 		jobs.Put(job)
 
-		// 后台执行 ffmpeg
+		// 按 preset 构建命令，参数不合法直接返回
+		cmd, err := pr.Build(job.InputPath, job.OutputPath, params)
+		if err != nil {
+			jobs.Delete(job.ID)
+			if cleanup != nil {
+				cleanup()
+			}
+			_ = os.Remove(job.OutputPath)
+			c.HTML(http.StatusBadRequest, "index.html", PageData{OK: false, Error: err.Error()})
+			return
+		}
+
+		// 交给队列执行，同时运行的 ffmpeg 数量受 worker 数限制
+		if _, err := ffQueue.Submit(queue.Request{ID: job.ID, Command: cmd}); err != nil {
+			jobs.Delete(job.ID)
+			if cleanup != nil {
+				cleanup()
+			}
+			_ = os.Remove(job.OutputPath)
+			c.HTML(http.StatusServiceUnavailable, "index.html", PageData{OK: false, Error: "提交任务失败: " + err.Error()})
+			return
+		}
+		events, _, _ := ffQueue.Subscribe(job.ID)
+
+		// 把队列事件转成 SSE 广播
 		go func() {
 			defer func() {
 				// 清理上传临时输入
@@ -375,52 +409,56 @@ func main() {
 				time.AfterFunc(30*time.Minute, func() {
 					_ = os.Remove(job.OutputPath)
 					jobs.Delete(job.ID)
+					_ = ffQueue.Forget(job.ID)
 				})
 			}()
 
-			job.Status = "running"
-			job.broadcast(sseEvent{Event: "status", Data: "running"})
-
-			// 构建命令（按你的 ffmpeg preset）
-			var cmd *ffmpeg.FFmpegCommand
-			switch action {
-			case "extract_aac":
-				cmd = ffmpeg.PresetExtractAAC(job.InputPath, job.OutputPath, abitrate)
-			case "snapshot":
-				cmd = ffmpeg.PresetSnapshot(job.InputPath, job.OutputPath, atSec)
-			case "remux":
-				cmd = ffmpeg.PresetRemux(job.InputPath, job.OutputPath)
-			default:
-				cmd = ffmpeg.PresetTranscodeMP4H264AAC(job.InputPath, job.OutputPath, crf, preset)
-			}
+			for ev := range events {
+				info := ev.Job
+				if ev.Type == queue.EventProgress {
+					p := info.Progress
+					b, _ := json.Marshal(map[string]any{
+						"frame":       p.Frame,
+						"fps":         p.FPS,
+						"out_time_ms": p.OutTimeMs,
+						"speed":       p.Speed,
+						"percent":     p.Percent,
+						"eta_sec":     p.ETA.Seconds(),
+					})
+					job.broadcast(sseEvent{Event: "progress", Data: string(b)})
+					continue
+				}
 
-			// 执行 + progress
-			_, runErr := ffTool.RunWithProgress(context.Background(), cmd, func(p ffmpeg.FFmpegProgress) error {
-				b, _ := json.Marshal(map[string]any{
-					"frame":       p.Frame,
-					"fps":         p.FPS,
-					"out_time_ms": p.OutTimeMs,
-					"speed":       p.Speed,
-				})
-				job.broadcast(sseEvent{Event: "progress", Data: string(b)})
-				return nil
-			})
-
-			if runErr != nil {
-				job.Status = "error"
-				job.ErrText = runErr.Error()
-				job.broadcast(sseEvent{Event: "status", Data: "error"})
-				job.broadcast(sseEvent{Event: "fferror", Data: job.ErrText})
-				return
+				switch info.Status {
+				case queue.StatusQueued:
+					job.Status = "queued"
+					job.broadcast(sseEvent{Event: "status", Data: "queued"})
+				case queue.StatusRunning:
+					job.Status = "running"
+					job.broadcast(sseEvent{Event: "status", Data: "running"})
+				case queue.StatusDone:
+					job.Status = "done"
+					job.broadcast(sseEvent{Event: "status", Data: "done"})
+					done := map[string]any{
+						"download": "/ffmpeg/download/" + job.ID,
+						"name":     job.OutputName,
+					}
+					if r := info.Result; r != nil {
+						done["wall_sec"] = r.WallTime.Seconds()
+						done["cpu_sec"] = r.CPUTime().Seconds()
+						done["max_rss"] = r.MaxRSS
+					}
+					donePayload, _ := json.Marshal(done)
+					job.broadcast(sseEvent{Event: "done", Data: string(donePayload)})
+				case queue.StatusFailed, queue.StatusCanceled:
+					job.Status = "error"
+					if info.Err != nil {
+						job.ErrText = info.Err.Error()
+					}
+					job.broadcast(sseEvent{Event: "status", Data: "error"})
+					job.broadcast(sseEvent{Event: "fferror", Data: job.ErrText})
+				}
 			}
-
-			job.Status = "done"
-			job.broadcast(sseEvent{Event: "status", Data: "done"})
-			donePayload, _ := json.Marshal(map[string]any{
-				"download": "/ffmpeg/download/" + job.ID,
-				"name":     job.OutputName,
-			})
-			job.broadcast(sseEvent{Event: "done", Data: string(donePayload)})
 		}()
 
 		// 直接渲染同一页，让前端用 SSE 订阅 job
@@ -0,0 +1,163 @@
+// Package fferr 是 ffmpeg / ffprobe 共用的错误类型和 stderr 分类规则。
+// 一般直接用 ffmpeg.Error / ffprobe.Error 及其中的 ErrXxx 别名即可。
+package fferr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// 分类哨兵：errors.Is(err, ErrInputNotFound) 判断类别，errors.As 到 *Error 取退出码/stderr/命令行
+var (
+	ErrInputNotFound    = errors.New("input not found")
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrInvalidData      = errors.New("invalid or corrupt data")
+	ErrUnknownEncoder   = errors.New("unknown encoder")
+	ErrUnknownDecoder   = errors.New("unknown decoder")
+	ErrUnsupportedCodec = errors.New("codec not supported by container")
+	ErrInvalidArgument  = errors.New("invalid option or argument")
+	ErrFilter           = errors.New("filter graph error")
+	ErrOutputExists     = errors.New("output already exists")
+	ErrNetwork          = errors.New("network error")
+	ErrTimeout          = errors.New("timed out")
+	ErrCanceled         = errors.New("canceled")
+	// ErrResourceLimit 超出 proc.Limits 的内存 / CPU 时间限制
+	ErrResourceLimit = errors.New("resource limit exceeded")
+	// ErrStoppedEarly 取消/超时时 ffmpeg 被优雅停止：输出已正常收尾可播放，但内容不完整
+	ErrStoppedEarly = errors.New("stopped early, partial output finalized")
+)
+
+// StderrTailBytes 是 Error.Stderr 保留的最大长度
+const StderrTailBytes = 4096
+
+// Error 是一次 ffmpeg / ffprobe 执行失败的详情
+type Error struct {
+	Tool     string   // "ffmpeg" / "ffprobe"
+	Kind     error    // 上面的某个哨兵；无法分类时为 nil
+	ExitCode int      // 进程退出码；被信号杀掉或未启动为 -1
+	Args     []string // 完整命令行（含可执行文件）
+	Stderr   string   // stderr 末尾部分
+	Err      error    // 底层错误（*exec.ExitError / context 错误等，实现 ExitCode() int 的都能取到退出码）
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Tool)
+	switch {
+	case e.Kind == ErrTimeout:
+		b.WriteString(" timed out")
+	case e.Kind == ErrCanceled:
+		b.WriteString(" canceled")
+	case e.Kind == ErrStoppedEarly:
+		b.WriteString(" " + ErrStoppedEarly.Error())
+	default:
+		b.WriteString(" failed")
+		if e.Kind != nil {
+			b.WriteString(": " + e.Kind.Error())
+		}
+	}
+	if e.Err != nil {
+		b.WriteString(" (" + e.Err.Error() + ")")
+	}
+	if e.Stderr != "" {
+		b.WriteString("; stderr=" + e.Stderr)
+	}
+	return b.String()
+}
+
+func (e *Error) Unwrap() []error {
+	var out []error
+	if e.Kind != nil {
+		out = append(out, e.Kind)
+	}
+	if e.Err != nil {
+		out = append(out, e.Err)
+	}
+	return out
+}
+
+// CommandLine 返回可读的命令行，便于日志
+func (e *Error) CommandLine() string {
+	return strings.Join(e.Args, " ")
+}
+
+// New 根据执行结果构造 *Error：
+// ctxErr 为执行用的 context 的 Err()，优先判定超时/取消；否则按 stderr 分类
+func New(tool string, args []string, stderr string, runErr, ctxErr error) *Error {
+	e := &Error{
+		Tool:     tool,
+		ExitCode: -1,
+		Args:     args,
+		Stderr:   Tail(strings.TrimSpace(stderr), StderrTailBytes),
+		Err:      runErr,
+	}
+	var ec interface{ ExitCode() int }
+	if errors.As(runErr, &ec) {
+		e.ExitCode = ec.ExitCode()
+	}
+	var ee *exec.ExitError
+	errors.As(runErr, &ee)
+	switch {
+	case errors.Is(ctxErr, context.DeadlineExceeded):
+		e.Kind = ErrTimeout
+	case errors.Is(ctxErr, context.Canceled):
+		e.Kind = ErrCanceled
+	case ee != nil && cpuLimitExceeded(ee):
+		e.Kind = ErrResourceLimit
+	default:
+		e.Kind = Classify(stderr)
+	}
+	return e
+}
+
+type rule struct {
+	kind     error
+	patterns []string
+}
+
+// 顺序有意义：越具体的放前面
+var rules = []rule{
+	{ErrResourceLimit, []string{"Cannot allocate memory", "Out of memory", "out of memory"}},
+	{ErrOutputExists, []string{"already exists. Exiting"}},
+	{ErrUnknownEncoder, []string{"Unknown encoder", "Encoder not found"}},
+	{ErrUnknownDecoder, []string{"Unknown decoder", "Decoder not found"}},
+	{ErrUnsupportedCodec, []string{"Could not find tag for codec", "codec not currently supported in container", "not supported by the", "Could not write header",
+		"Subtitle encoding currently only possible from text to text or bitmap to bitmap"}},
+	{ErrFilter, []string{"No such filter", "Error initializing filter", "Error reinitializing filters", "Error parsing filterchain", "Invalid stream specifier"}},
+	{ErrInvalidArgument, []string{"Unrecognized option", "Option not found", "Invalid argument", "Error splitting the argument list", "Missing argument for option",
+		"Unknown input format", "Unknown output format", "Requested output format", "matches no streams"}},
+	{ErrPermissionDenied, []string{"Permission denied", "Operation not permitted", "403 Forbidden", "401 Unauthorized"}},
+	{ErrInputNotFound, []string{"No such file or directory", "404 Not Found", "does not exist"}},
+	{ErrNetwork, []string{"Connection refused", "Connection timed out", "Connection reset by peer", "Network is unreachable", "Name or service not known", "Failed to resolve hostname", "Server returned 5"}},
+	{ErrInvalidData, []string{"Invalid data found when processing input", "moov atom not found", "Invalid NAL unit", "error while decoding", "corrupt", "Truncating packet"}},
+}
+
+// Classify 按 ffmpeg/ffprobe stderr 的常见文案归类，无法识别返回 nil
+func Classify(stderr string) error {
+	if stderr == "" {
+		return nil
+	}
+	for _, r := range rules {
+		for _, p := range r.patterns {
+			if strings.Contains(stderr, p) {
+				return r.kind
+			}
+		}
+	}
+	return nil
+}
+
+// Tail 取 s 的最后 n 字节（按行边界对齐，避免截断半行）
+func Tail(s string, n int) string {
+	if n <= 0 || len(s) <= n {
+		return s
+	}
+	s = s[len(s)-n:]
+	if i := strings.IndexByte(s, '\n'); i >= 0 && i < len(s)-1 {
+		s = s[i+1:]
+	}
+	return fmt.Sprintf("...%s", s)
+}
@@ -0,0 +1,7 @@
+//go:build !unix
+
+package fferr
+
+import "os/exec"
+
+func cpuLimitExceeded(ee *exec.ExitError) bool { return false }
@@ -0,0 +1,14 @@
+//go:build unix
+
+package fferr
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// cpuLimitExceeded 进程因 RLIMIT_CPU 被 SIGXCPU / 硬限制 SIGKILL 终止；后者无法和普通 kill 区分，只认 SIGXCPU
+func cpuLimitExceeded(ee *exec.ExitError) bool {
+	ws, ok := ee.Sys().(syscall.WaitStatus)
+	return ok && ws.Signaled() && ws.Signal() == syscall.SIGXCPU
+}
@@ -5,58 +5,234 @@ import (
 	"strings"
 )
 
+// FFmpegCommand 按 ffmpeg 的结构组织参数：
+//
+//	ffmpeg [global] ([input opts] -i input)... [-filter_complex ...] ([output opts] output)...
+//
+// 链式调用里的选项（VideoCodec/Map/StartAt...）先进入 pending，
+// 由紧随其后的 Input 或 Output 收走，等价于命令行上"选项写在文件前面"的语义。
+// 需要明确作用对象时用 AddInput / AddOutput 拿到单独的 spec 再设置。
 type FFmpegCommand struct {
-	args []string
+	global  []string
+	inputs  []*InputSpec
+	outputs []*OutputSpec
+	pending []string
+
+	// 下一个 Output 的 -vf / -af
+	vf *FilterChain
+	af *FilterChain
+	// -filter_complex 是全局的，写在所有输入之后、第一个输出之前
+	graph *FilterGraph
+
+	// 运行前检查的版本/库要求，见 Require
+	requires []Requirement
 }
 
 func NewFFmpegCommand() *FFmpegCommand {
 	// 默认覆盖输出，避免交互卡住
-	return &FFmpegCommand{args: []string{"-y"}}
+	return &FFmpegCommand{global: []string{"-y"}}
 }
 
 func (c *FFmpegCommand) Args() []string {
-	out := make([]string, len(c.args))
-	copy(out, c.args)
-	return out
+	return c.args(0)
+}
+
+// args 生成命令行；threads > 0 时给每个输出加 -threads、全局加 -filter_threads（已显式设置的不覆盖）
+func (c *FFmpegCommand) args(threads int) []string {
+	out := make([]string, 0, 16)
+	out = append(out, c.global...)
+	if threads > 0 && !hasOpt(c.global, "-filter_threads") {
+		out = append(out, "-filter_threads", itoa(threads))
+	}
+	for _, in := range c.inputs {
+		out = append(out, in.args()...)
+	}
+	if !c.graph.Empty() {
+		out = append(out, "-filter_complex", c.graph.String())
+	}
+	for _, o := range c.outputs {
+		if threads > 0 && !hasOpt(o.opts, "-threads") {
+			out = append(out, "-threads", itoa(threads))
+		}
+		out = append(out, o.args()...)
+	}
+	// 没有被 Input/Output 收走的选项和滤镜
+	n := len(c.pending)
+	if len(c.outputs) > 0 || n == 0 {
+		// 最后一个输出之后的选项 ffmpeg 会忽略，Validate 会报 trailing-options
+		out = append(out, c.pending...)
+		return append(out, filterArgs(c.vf, c.af)...)
+	}
+	// 全部用 AppendArgs 拼的旧式命令，最后一个参数按输出路径处理，
+	// -threads 和 -vf / -af 插在它前面，和旧版 Scale 等直接追加参数的位置一致
+	out = append(out, c.pending[:n-1]...)
+	if threads > 0 && !hasOpt(c.pending, "-threads") {
+		out = append(out, "-threads", itoa(threads))
+	}
+	out = append(out, filterArgs(c.vf, c.af)...)
+	return append(out, c.pending[n-1])
+}
+
+func hasOpt(opts []string, name string) bool {
+	for _, o := range opts {
+		if o == name {
+			return true
+		}
+	}
+	return false
 }
 
+// AppendArgs 追加原始参数，归属于下一个 Input 或 Output
 func (c *FFmpegCommand) AppendArgs(args ...string) *FFmpegCommand {
-	c.args = append(c.args, args...)
+	c.pending = append(c.pending, args...)
+	return c
+}
+
+// GlobalArgs 追加全局参数，总是写在所有输入之前
+func (c *FFmpegCommand) GlobalArgs(args ...string) *FFmpegCommand {
+	c.global = append(c.global, args...)
 	return c
 }
 
 func (c *FFmpegCommand) HideBanner() *FFmpegCommand {
-	return c.AppendArgs("-hide_banner")
+	return c.GlobalArgs("-hide_banner")
 }
 
 func (c *FFmpegCommand) LogLevel(level string) *FFmpegCommand {
 	// "error" / "warning" / "info" / "quiet"
-	return c.AppendArgs("-v", level)
+	return c.GlobalArgs("-v", level)
 }
 
+// Input 添加输入，之前 pending 的选项成为该输入的选项
 func (c *FFmpegCommand) Input(path string) *FFmpegCommand {
-	return c.AppendArgs("-i", path)
+	in := c.AddInput(path)
+	in.opts = append(in.opts, c.pending...)
+	c.pending = nil
+	return c
+}
+
+// AddInput 添加一个输入并返回它，用于单独设置 seek/时长/格式等
+func (c *FFmpegCommand) AddInput(path string) *InputSpec {
+	in := &InputSpec{Path: path, index: len(c.inputs)}
+	c.inputs = append(c.inputs, in)
+	return in
+}
+
+// PipeInput 以 stdin（pipe:0）作为输入，配合 RunOptions.Stdin / RunStream；
+// 裸流或无法探测的格式需要给 format
+func (c *FFmpegCommand) PipeInput(format string) *InputSpec {
+	in := c.AddInput("pipe:0")
+	if format != "" {
+		in.Format(format)
+	}
+	return in
+}
+
+func (c *FFmpegCommand) Inputs() []*InputSpec {
+	out := make([]*InputSpec, len(c.inputs))
+	copy(out, c.inputs)
+	return out
+}
+
+// LastInput 返回最近添加的输入，没有则为 nil
+func (c *FFmpegCommand) LastInput() *InputSpec {
+	if len(c.inputs) == 0 {
+		return nil
+	}
+	return c.inputs[len(c.inputs)-1]
 }
 
 func (c *FFmpegCommand) Overwrite(on bool) *FFmpegCommand {
 	// 默认已经 -y，这里允许业务明确关闭
-	if on {
-		return c // already -y
-	}
-	// remove "-y" if present; then add "-n"
-	n := make([]string, 0, len(c.args))
-	for _, a := range c.args {
-		if a == "-y" {
+	n := make([]string, 0, len(c.global)+1)
+	for _, a := range c.global {
+		if a == "-y" || a == "-n" {
 			continue
 		}
 		n = append(n, a)
 	}
-	c.args = n
-	return c.AppendArgs("-n")
+	if on {
+		c.global = append([]string{"-y"}, n...)
+	} else {
+		c.global = append([]string{"-n"}, n...)
+	}
+	return c
 }
 
+// Output 添加输出，之前 pending 的选项和 -vf/-af 成为该输出的选项
 func (c *FFmpegCommand) Output(path string) *FFmpegCommand {
-	return c.AppendArgs(path)
+	o := c.AddOutput(path)
+	o.opts = append(o.opts, c.pending...)
+	o.vf, o.af = c.vf, c.af
+	c.pending = nil
+	c.vf, c.af = nil, nil
+	return c
+}
+
+// AddOutput 添加一个输出并返回它，用于单独设置编码/映射/滤镜等
+func (c *FFmpegCommand) AddOutput(path string) *OutputSpec {
+	o := &OutputSpec{Path: path, index: len(c.outputs)}
+	c.outputs = append(c.outputs, o)
+	return o
+}
+
+// PipeOutput 输出到 stdout（pipe:1），配合 RunOptions.Stdout / RunStream。
+// 管道不可 seek，必须指定 format；mp4/mov 会自动改成分片写出
+func (c *FFmpegCommand) PipeOutput(format string) *OutputSpec {
+	o := c.AddOutput("pipe:1").Format(format)
+	if format == "mp4" || format == "mov" {
+		o.Option("-movflags", "frag_keyframe+empty_moov")
+	}
+	return o
+}
+
+func (c *FFmpegCommand) Outputs() []*OutputSpec {
+	out := make([]*OutputSpec, len(c.outputs))
+	copy(out, c.outputs)
+	return out
+}
+
+// VideoFilter 往下一个输出的 -vf 链上追加滤镜，多次调用会合并成一个 -vf
+func (c *FFmpegCommand) VideoFilter(filters ...*Filter) *FFmpegCommand {
+	if c.vf == nil {
+		c.vf = NewFilterChain()
+	}
+	c.vf.Add(filters...)
+	return c
+}
+
+// AudioFilter 往下一个输出的 -af 链上追加滤镜
+func (c *FFmpegCommand) AudioFilter(filters ...*Filter) *FFmpegCommand {
+	if c.af == nil {
+		c.af = NewFilterChain()
+	}
+	c.af.Add(filters...)
+	return c
+}
+
+// FilterComplex 设置 -filter_complex；输出 pad 用 MapLabel 选取
+func (c *FFmpegCommand) FilterComplex(g *FilterGraph) *FFmpegCommand {
+	c.graph = g
+	return c
+}
+
+// FilterGraph 返回命令上的 filter_complex（没有则新建），便于直接 Chain
+func (c *FFmpegCommand) FilterGraph() *FilterGraph {
+	if c.graph == nil {
+		c.graph = NewFilterGraph()
+	}
+	return c.graph
+}
+
+func filterArgs(vf, af *FilterChain) []string {
+	var out []string
+	if vf.Len() > 0 {
+		out = append(out, "-vf", vf.String())
+	}
+	if af.Len() > 0 {
+		out = append(out, "-af", af.String())
+	}
+	return out
 }
 
 func (c *FFmpegCommand) VideoCodec(codec string) *FFmpegCommand {
@@ -86,9 +262,21 @@ func (c *FFmpegCommand) Map(spec string) *FFmpegCommand {
 	return c.AppendArgs("-map", spec)
 }
 
+// MapLabel 选取 filter_complex 的输出 pad，例如 MapLabel("out") => -map [out]
+func (c *FFmpegCommand) MapLabel(label string) *FFmpegCommand {
+	return c.Map("[" + trimLabel(label) + "]")
+}
+
 func (c *FFmpegCommand) Scale(w, h int) *FFmpegCommand {
-	// 若你需要叠加多个滤镜，可以做一个 FilterGraph builder；先给一个够用的
-	return c.AppendArgs("-vf", "scale="+itoa(w)+":"+itoa(h))
+	return c.VideoFilter(ScaleFilter(w, h))
+}
+
+func (c *FFmpegCommand) Crop(w, h, x, y string) *FFmpegCommand {
+	return c.VideoFilter(CropFilter(w, h, x, y))
+}
+
+func (c *FFmpegCommand) Pad(w, h, x, y, color string) *FFmpegCommand {
+	return c.VideoFilter(PadFilter(w, h, x, y, color))
 }
 
 func (c *FFmpegCommand) FPS(fps string) *FFmpegCommand {
@@ -96,11 +284,135 @@ func (c *FFmpegCommand) FPS(fps string) *FFmpegCommand {
 	return c.AppendArgs("-r", fps)
 }
 
+// StartAt 归属于下一个 Input（快速 seek）或 Output（解码后丢帧，精确但慢）；
+// 要对已添加的输入 seek，用 LastInput().Seek 或 AddInput(...).Seek
 func (c *FFmpegCommand) StartAt(seconds float64) *FFmpegCommand {
-	// -ss before -i: 快速 seek；这里给简单语义：业务自行控制插入位置时可用 AppendArgs
 	return c.AppendArgs("-ss", trimFloat(seconds))
 }
 
+// InputSpec 是一个输入文件及其选项（写在 -i 之前）
+type InputSpec struct {
+	Path string
+
+	index int
+	opts  []string
+}
+
+// Index 是该输入在命令中的序号，即 -map 里的 "0" / "1"
+func (in *InputSpec) Index() int { return in.index }
+
+func (in *InputSpec) Options() []string {
+	out := make([]string, len(in.opts))
+	copy(out, in.opts)
+	return out
+}
+
+func (in *InputSpec) Option(args ...string) *InputSpec {
+	in.opts = append(in.opts, args...)
+	return in
+}
+
+// Seek 输入侧 -ss：快速定位
+func (in *InputSpec) Seek(seconds float64) *InputSpec {
+	return in.Option("-ss", trimFloat(seconds))
+}
+
+// Duration 输入侧 -t：只读取这么长
+func (in *InputSpec) Duration(seconds float64) *InputSpec {
+	return in.Option("-t", trimFloat(seconds))
+}
+
+// Format 强制输入格式，例如 "concat" / "s16le"
+func (in *InputSpec) Format(f string) *InputSpec {
+	return in.Option("-f", f)
+}
+
+// Codec 指定解码器，stream 如 "v" / "a" / "v:0"
+func (in *InputSpec) Codec(stream, codec string) *InputSpec {
+	return in.Option("-c:"+stream, codec)
+}
+
+func (in *InputSpec) args() []string {
+	out := make([]string, 0, len(in.opts)+2)
+	out = append(out, in.opts...)
+	return append(out, "-i", in.Path)
+}
+
+// OutputSpec 是一个输出文件及其选项（写在路径之前）
+type OutputSpec struct {
+	Path string
+
+	index int
+	opts  []string
+	vf    *FilterChain
+	af    *FilterChain
+}
+
+func (o *OutputSpec) Index() int { return o.index }
+
+func (o *OutputSpec) Options() []string {
+	out := make([]string, len(o.opts))
+	copy(out, o.opts)
+	return out
+}
+
+func (o *OutputSpec) Option(args ...string) *OutputSpec {
+	o.opts = append(o.opts, args...)
+	return o
+}
+
+// Seek 输出侧 -ss：解码后丢弃之前的帧
+func (o *OutputSpec) Seek(seconds float64) *OutputSpec {
+	return o.Option("-ss", trimFloat(seconds))
+}
+
+func (o *OutputSpec) Duration(seconds float64) *OutputSpec {
+	return o.Option("-t", trimFloat(seconds))
+}
+
+func (o *OutputSpec) Format(f string) *OutputSpec {
+	return o.Option("-f", f)
+}
+
+// Codec 指定编码器，stream 如 "v" / "a" / "s" / "v:0"
+func (o *OutputSpec) Codec(stream, codec string) *OutputSpec {
+	return o.Option("-c:"+stream, codec)
+}
+
+func (o *OutputSpec) VideoCodec(codec string) *OutputSpec { return o.Codec("v", codec) }
+func (o *OutputSpec) AudioCodec(codec string) *OutputSpec { return o.Codec("a", codec) }
+
+func (o *OutputSpec) Map(spec string) *OutputSpec {
+	return o.Option("-map", spec)
+}
+
+func (o *OutputSpec) MapLabel(label string) *OutputSpec {
+	return o.Map("[" + trimLabel(label) + "]")
+}
+
+func (o *OutputSpec) VideoFilter(filters ...*Filter) *OutputSpec {
+	if o.vf == nil {
+		o.vf = NewFilterChain()
+	}
+	o.vf.Add(filters...)
+	return o
+}
+
+func (o *OutputSpec) AudioFilter(filters ...*Filter) *OutputSpec {
+	if o.af == nil {
+		o.af = NewFilterChain()
+	}
+	o.af.Add(filters...)
+	return o
+}
+
+func (o *OutputSpec) args() []string {
+	out := make([]string, 0, len(o.opts)+5)
+	out = append(out, o.opts...)
+	out = append(out, filterArgs(o.vf, o.af)...)
+	return append(out, o.Path)
+}
+
 func trimFloat(f float64) string {
 	s := fmt.Sprintf("%.3f", f)
 	s = strings.TrimRight(s, "0")
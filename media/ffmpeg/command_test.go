@@ -0,0 +1,28 @@
+package ffmpeg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestArgsLegacyChainKeepsFiltersBeforeOutput(t *testing.T) {
+	cmd := NewFFmpegCommand().AppendArgs("-i", "in.mp4").Scale(10, 10).AppendArgs("out.mp4")
+
+	want := []string{"-y", "-i", "in.mp4", "-vf", "scale=10:10", "out.mp4"}
+	if got := cmd.Args(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Args() = %q, want %q", got, want)
+	}
+	want = []string{"-y", "-filter_threads", "2", "-i", "in.mp4", "-threads", "2", "-vf", "scale=10:10", "out.mp4"}
+	if got := cmd.args(2); !reflect.DeepEqual(got, want) {
+		t.Errorf("args(2) = %q, want %q", got, want)
+	}
+}
+
+func TestArgsStructured(t *testing.T) {
+	cmd := NewFFmpegCommand().Input("in.mp4").Scale(10, 10).VideoCodec("libx264").Output("out.mp4")
+
+	want := []string{"-y", "-i", "in.mp4", "-c:v", "libx264", "-vf", "scale=10:10", "out.mp4"}
+	if got := cmd.Args(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Args() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,380 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LingByte/LingConvert/media/ffprobe"
+)
+
+type ConcatMode int
+
+const (
+	// ConcatAuto 输入参数一致时用 concat demuxer 无损拼接，否则用 concat 滤镜重新编码
+	ConcatAuto ConcatMode = iota
+	// ConcatDemuxer 强制 -f concat + stream copy；输入不一致时返回 ErrConcatIncompatible
+	ConcatDemuxer
+	// ConcatFilter 强制用 concat 滤镜，先统一分辨率/帧率/采样率再编码
+	ConcatFilter
+)
+
+func (m ConcatMode) String() string {
+	switch m {
+	case ConcatDemuxer:
+		return "demuxer"
+	case ConcatFilter:
+		return "filter"
+	}
+	return "auto"
+}
+
+// ErrConcatIncompatible 输入的编码参数不一致，不能用 concat demuxer 直接拼接
+var ErrConcatIncompatible = errors.New("concat: inputs are not compatible for stream copy")
+
+// ConcatOptions 拼接参数；目标规格为空时取第一个输入的
+type ConcatOptions struct {
+	Mode ConcatMode
+
+	// 以下只用于 concat 滤镜模式
+	Width, Height int    // 统一的分辨率，保持比例缩放后补黑边
+	FPS           string // 统一的帧率，例如 "30" / "30000/1001"
+	PixFmt        string // 默认 yuv420p
+	SampleRate    int    // 默认第一个有音频的输入的采样率，都没有则 48000
+	ChannelLayout string // 默认 stereo
+	VideoCodec    string // 默认 libx264
+	AudioCodec    string // 默认 aac
+	CRF           int    // 默认 23
+	Preset        string // 默认 medium
+
+	FastStart bool // mp4 输出时加 +faststart
+}
+
+// ConcatResult 实际使用的方式；Reason 说明自动模式为什么没用 demuxer
+type ConcatResult struct {
+	Mode     ConcatMode
+	Reason   string
+	Progress FFmpegProgress
+}
+
+// Concat 把 inputs 按顺序拼成 output。
+// 用 ffprobe 比较每个输入的流布局、编码、分辨率、像素格式、采样率和声道，一致则走 concat demuxer（不重新编码），
+// 否则走 concat 滤镜：缩放补边、统一帧率和采样率，缺音频的输入补静音。
+// demuxer 的列表文件写在私有临时目录里，结束后删除。
+func (t *FFmpegTool) Concat(
+	ctx context.Context,
+	inputs []string,
+	output string,
+	opt ConcatOptions,
+	onProgress func(p FFmpegProgress) error,
+) (*ConcatResult, error) {
+	if len(inputs) == 0 {
+		return nil, errors.New("concat: no inputs")
+	}
+	prober := t.prober()
+	infos := make([]*ffprobe.FFProbeJSON, len(inputs))
+	for i, in := range inputs {
+		info, err := prober.Probe(ctx, in)
+		if err != nil {
+			return nil, fmt.Errorf("concat: probe input #%d: %w", i, err)
+		}
+		infos[i] = info
+	}
+	var total time.Duration
+	for _, info := range infos {
+		total += probedDuration(info)
+	}
+	res := &ConcatResult{Mode: opt.Mode}
+	if opt.Mode != ConcatFilter {
+		reason := concatIncompatibility(infos)
+		if reason == "" {
+			reason = concatListProblem(inputs)
+		}
+		switch {
+		case reason == "":
+			res.Mode = ConcatDemuxer
+		case opt.Mode == ConcatDemuxer:
+			return nil, fmt.Errorf("%w: %s", ErrConcatIncompatible, reason)
+		default:
+			res.Mode, res.Reason = ConcatFilter, reason
+		}
+	}
+
+	runOpt := RunOptions{OnProgress: onProgress, TotalDuration: total}
+	var err error
+	if res.Mode == ConcatDemuxer {
+		res.Progress, err = t.concatDemuxer(ctx, inputs, output, opt, runOpt)
+	} else {
+		var cmd *FFmpegCommand
+		if cmd, err = concatFilterCommand(inputs, infos, output, opt); err == nil {
+			res.Progress, err = t.RunWithOptions(ctx, cmd, runOpt)
+		}
+	}
+	if err != nil {
+		return res, fmt.Errorf("concat (%s): %w", res.Mode, err)
+	}
+	return res, nil
+}
+
+func (t *FFmpegTool) concatDemuxer(ctx context.Context, inputs []string, output string, opt ConcatOptions, runOpt RunOptions) (FFmpegProgress, error) {
+	dir, err := os.MkdirTemp("", "ffconcat-*")
+	if err != nil {
+		return FFmpegProgress{}, fmt.Errorf("create list dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	list := filepath.Join(dir, "list.txt")
+	data, err := ConcatList(inputs)
+	if err != nil {
+		return FFmpegProgress{}, err
+	}
+	if err := os.WriteFile(list, data, 0o600); err != nil {
+		return FFmpegProgress{}, fmt.Errorf("write list file: %w", err)
+	}
+
+	cmd := NewFFmpegCommand().HideBanner().LogLevel("error")
+	in := cmd.AddInput(list).Format("concat").Option("-safe", "0")
+	if hasURL(inputs) {
+		in.Option("-protocol_whitelist", "file,http,https,tcp,tls,crypto")
+	}
+	o := cmd.AddOutput(output).Map("0").Option("-c", "copy")
+	if opt.FastStart {
+		o.Option("-movflags", "+faststart")
+	}
+	return t.RunWithOptions(ctx, cmd, runOpt)
+}
+
+// ConcatList 生成 concat demuxer 的列表文件内容（ffconcat 格式）。
+// 本地路径转成绝对路径（相对路径会按列表文件所在目录解析），用单引号包住，内部的单引号写成
+//
+//	'\''
+//
+// 路径里有换行时无法表示，返回错误
+func ConcatList(paths []string) ([]byte, error) {
+	var b strings.Builder
+	b.WriteString("ffconcat version 1.0\n")
+	for _, p := range paths {
+		if strings.ContainsAny(p, "\r\n") {
+			return nil, fmt.Errorf("concat: path %q contains a newline", p)
+		}
+		if !isURL(p) {
+			abs, err := filepath.Abs(p)
+			if err != nil {
+				return nil, fmt.Errorf("concat: %w", err)
+			}
+			p = abs
+		}
+		b.WriteString("file '" + strings.ReplaceAll(p, "'", `'\''`) + "'\n")
+	}
+	return []byte(b.String()), nil
+}
+
+func concatListProblem(paths []string) string {
+	if _, err := ConcatList(paths); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+func isURL(p string) bool {
+	i := strings.Index(p, "://")
+	return i > 1 && !strings.ContainsAny(p[:i], `/\`)
+}
+
+func hasURL(paths []string) bool {
+	for _, p := range paths {
+		if isURL(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// concatIncompatibility 比较每个输入和第一个输入的流，返回第一处不一致的描述；一致返回 ""
+func concatIncompatibility(infos []*ffprobe.FFProbeJSON) string {
+	first := infos[0].Streams
+	for i, info := range infos[1:] {
+		if len(info.Streams) != len(first) {
+			return fmt.Sprintf("input #%d has %d streams, input #0 has %d", i+1, len(info.Streams), len(first))
+		}
+		for k, s := range info.Streams {
+			if d := streamDiff(first[k], s); d != "" {
+				return fmt.Sprintf("input #%d stream %d: %s", i+1, k, d)
+			}
+		}
+	}
+	return ""
+}
+
+func streamDiff(a, b ffprobe.Stream) string {
+	type field struct{ name, a, b string }
+	fields := []field{
+		{"type", a.CodecType, b.CodecType},
+		{"codec", a.CodecName, b.CodecName},
+	}
+	switch a.CodecType {
+	case "video":
+		fields = append(fields,
+			field{"resolution", fmt.Sprintf("%dx%d", a.Width, a.Height), fmt.Sprintf("%dx%d", b.Width, b.Height)},
+			field{"pix_fmt", a.PixFmt, b.PixFmt},
+			field{"profile", a.Profile, b.Profile},
+			field{"time_base", a.TimeBase, b.TimeBase},
+		)
+	case "audio":
+		fields = append(fields,
+			field{"sample_rate", a.SampleRate, b.SampleRate},
+			field{"channels", strconv.Itoa(a.Channels), strconv.Itoa(b.Channels)},
+		)
+	}
+	for _, f := range fields {
+		if f.a != f.b {
+			return fmt.Sprintf("%s %s != %s", f.name, f.b, f.a)
+		}
+	}
+	return ""
+}
+
+func (o ConcatOptions) withDefaults(infos []*ffprobe.FFProbeJSON) ConcatOptions {
+	for _, info := range infos {
+		v := info.FirstVideo()
+		if v == nil {
+			continue
+		}
+		if o.Width <= 0 || o.Height <= 0 {
+			o.Width, o.Height = v.Width, v.Height
+		}
+		if o.FPS == "" {
+			o.FPS = v.AvgFrameRate
+			if o.FPS == "" || o.FPS == "0/0" {
+				o.FPS = v.RFrameRate
+			}
+		}
+		break
+	}
+	if o.FPS == "" || o.FPS == "0/0" {
+		o.FPS = "30"
+	}
+	if o.SampleRate <= 0 {
+		for _, info := range infos {
+			if a := info.FirstAudio(); a != nil {
+				o.SampleRate, _ = strconv.Atoi(a.SampleRate)
+				break
+			}
+		}
+	}
+	if o.SampleRate <= 0 {
+		o.SampleRate = 48000
+	}
+	if o.PixFmt == "" {
+		o.PixFmt = "yuv420p"
+	}
+	if o.ChannelLayout == "" {
+		o.ChannelLayout = "stereo"
+	}
+	if o.VideoCodec == "" {
+		o.VideoCodec = "libx264"
+	}
+	if o.AudioCodec == "" {
+		o.AudioCodec = "aac"
+	}
+	if o.CRF <= 0 {
+		o.CRF = 23
+	}
+	if o.Preset == "" {
+		o.Preset = "medium"
+	}
+	return o
+}
+
+// concatFilterCommand 每个输入先统一规格再接 concat 滤镜：
+//
+//	[0:v]scale,pad,setsar,fps,format[v0];[0:a]aresample,aformat[a0];...;[v0][a0][v1][a1]concat=n=2:v=1:a=1[v][a]
+func concatFilterCommand(inputs []string, infos []*ffprobe.FFProbeJSON, output string, opt ConcatOptions) (*FFmpegCommand, error) {
+	opt = opt.withDefaults(infos)
+	var hasVideo, hasAudio bool
+	for _, info := range infos {
+		hasVideo = hasVideo || info.FirstVideo() != nil
+		hasAudio = hasAudio || info.FirstAudio() != nil
+	}
+	if !hasVideo && !hasAudio {
+		return nil, errors.New("concat: inputs have no audio or video streams")
+	}
+
+	cmd := NewFFmpegCommand().HideBanner().LogLevel("error")
+	for _, in := range inputs {
+		cmd.AddInput(in)
+	}
+	g := cmd.FilterGraph()
+	w, h := itoa(opt.Width), itoa(opt.Height)
+	var pads []string
+	for i, info := range infos {
+		if hasVideo {
+			if info.FirstVideo() == nil {
+				return nil, fmt.Errorf("concat: input #%d has no video stream", i)
+			}
+			label := "v" + itoa(i)
+			g.Chain(
+				NewFilter("scale", w, h).Opt("force_original_aspect_ratio", "decrease").In(itoa(i)+":v:0"),
+				NewFilter("pad", w, h, "(ow-iw)/2", "(oh-ih)/2"),
+				NewFilter("setsar", "1"),
+				NewFilter("fps", opt.FPS),
+				NewFilter("format", opt.PixFmt).Out(label),
+			)
+			pads = append(pads, label)
+		}
+		if hasAudio {
+			label := "a" + itoa(i)
+			aformat := NewFilter("aformat").
+				Opt("sample_fmts", "fltp").
+				Opt("sample_rates", itoa(opt.SampleRate)).
+				Opt("channel_layouts", opt.ChannelLayout).
+				Out(label)
+			if info.FirstAudio() != nil {
+				g.Chain(NewFilter("aresample", itoa(opt.SampleRate)).In(itoa(i)+":a:0"), aformat)
+			} else {
+				// 没有音频的片段补同样长度的静音
+				g.Chain(
+					NewFilter("anullsrc").Opt("r", itoa(opt.SampleRate)).Opt("cl", opt.ChannelLayout),
+					NewFilter("atrim").Opt("duration", trimFloat(probedDuration(info).Seconds())),
+					aformat,
+				)
+			}
+			pads = append(pads, label)
+		}
+	}
+	concat := NewFilter("concat").Opt("n", itoa(len(inputs))).Opt("v", boolInt(hasVideo)).Opt("a", boolInt(hasAudio)).In(pads...)
+	o := cmd.AddOutput(output)
+	if hasVideo {
+		concat.Out("v")
+		o.MapLabel("v").VideoCodec(opt.VideoCodec).Option("-crf", itoa(opt.CRF), "-preset", opt.Preset)
+	}
+	if hasAudio {
+		concat.Out("a")
+		o.MapLabel("a").AudioCodec(opt.AudioCodec)
+	}
+	g.Chain(concat)
+	if opt.FastStart {
+		o.Option("-movflags", "+faststart")
+	}
+	return cmd, nil
+}
+
+func boolInt(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+// probedDuration ffprobe 给出的容器时长，缺失时为 0
+func probedDuration(info *ffprobe.FFProbeJSON) time.Duration {
+	secs, err := strconv.ParseFloat(info.Format.Duration, 64)
+	if err != nil || secs <= 0 {
+		return 0
+	}
+	return time.Duration(secs * float64(time.Second))
+}
@@ -0,0 +1,131 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+type DASHOptions struct {
+	OutputDir    string // 必填：mpd、init 和分片都写在这里
+	ManifestName string // 默认 manifest.mpd
+
+	Renditions []Rendition // 每档一个视频 representation
+
+	// 音频单独一个 adaptation set，只编码一路
+	AudioCodec   string // 默认 aac
+	AudioBitrate string // 默认 128k
+	NoAudio      bool
+
+	SegmentDuration float64 // 默认 4 秒
+	KeyframeAlign   bool
+	Preset          string // x264 preset，默认 medium
+
+	// HLSPlaylists 同时输出引用同一批 CMAF 分片的 HLS playlist（dash muxer 的 -hls_playlist）
+	HLSPlaylists bool
+	HLSMaster    string // 默认 master.m3u8
+}
+
+func (o DASHOptions) manifestName() string {
+	if o.ManifestName == "" {
+		return "manifest.mpd"
+	}
+	return o.ManifestName
+}
+
+func (o DASHOptions) ManifestPath() string {
+	return filepath.Join(o.OutputDir, o.manifestName())
+}
+
+// HLSMasterPath 仅在 HLSPlaylists 开启时有意义
+func (o DASHOptions) HLSMasterPath() string {
+	name := o.HLSMaster
+	if name == "" {
+		name = "master.m3u8"
+	}
+	return filepath.Join(o.OutputDir, name)
+}
+
+// PresetDASH 一次 ffmpeg 输出 CMAF（fMP4）分片 + .mpd，可选同时写 HLS playlist 共用分片
+func PresetDASH(input string, opt DASHOptions) (*FFmpegCommand, error) {
+	if opt.OutputDir == "" {
+		return nil, errors.New("dash: OutputDir is required")
+	}
+	if len(opt.Renditions) == 0 {
+		return nil, errors.New("dash: at least one rendition is required")
+	}
+	segDur := opt.SegmentDuration
+	if segDur <= 0 {
+		segDur = 4
+	}
+	preset := opt.Preset
+	if preset == "" {
+		preset = "medium"
+	}
+
+	cmd := NewFFmpegCommand().
+		HideBanner().
+		LogLevel("error").
+		RequireVersion("4.2", "dash -seg_duration / -dash_segment_type").
+		Input(input)
+
+	out := cmd.AddOutput(opt.ManifestPath())
+	if _, err := mapRenditionVideo(cmd, out, opt.Renditions); err != nil {
+		return nil, fmt.Errorf("dash: %w", err)
+	}
+
+	adaptationSets := "id=0,streams=v"
+	if !opt.NoAudio {
+		acodec := opt.AudioCodec
+		if acodec == "" {
+			acodec = "aac"
+		}
+		abitrate := opt.AudioBitrate
+		if abitrate == "" {
+			abitrate = "128k"
+		}
+		out.Map("0:a:0").
+			Option("-c:a", acodec, "-b:a", abitrate)
+		adaptationSets += " id=1,streams=a"
+	}
+
+	out.Option("-preset", preset)
+	if opt.KeyframeAlign {
+		alignKeyframes(out, segDur)
+	}
+
+	out.Format("dash").
+		Option("-seg_duration", trimFloat(segDur),
+			"-dash_segment_type", "mp4",
+			"-use_template", "1",
+			"-use_timeline", "1",
+			"-init_seg_name", "init-$RepresentationID$.m4s",
+			"-media_seg_name", "chunk-$RepresentationID$-$Number%05d$.m4s",
+			"-adaptation_sets", adaptationSets)
+	if opt.HLSPlaylists {
+		// -hls_master_name 从 4.4 开始才有，旧版本会直接报 Unrecognized option
+		cmd.RequireVersion("4.4", "dash -hls_master_name")
+		out.Option("-hls_playlist", "1",
+			"-hls_master_name", filepath.Base(opt.HLSMasterPath()))
+	}
+	return cmd, nil
+}
+
+// PackageDASH 创建输出目录后执行 PresetDASH，进度通过 RunWithProgress 回调
+func (t *FFmpegTool) PackageDASH(
+	ctx context.Context,
+	input string,
+	opt DASHOptions,
+	onProgress func(p FFmpegProgress) error,
+) (FFmpegProgress, error) {
+	cmd, err := PresetDASH(input, opt)
+	if err != nil {
+		return FFmpegProgress{}, err
+	}
+	if err := os.MkdirAll(opt.OutputDir, 0o755); err != nil {
+		return FFmpegProgress{}, fmt.Errorf("dash: create output dir: %w", err)
+	}
+	return t.RunWithProgress(ctx, cmd, onProgress)
+}
@@ -0,0 +1,55 @@
+package ffmpeg_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/LingByte/LingConvert/media/ffmpeg"
+	fftest "github.com/LingByte/LingConvert/media/testing"
+)
+
+func TestPresetDASHVersionGate(t *testing.T) {
+	requirementDiags := func(version string, hls bool) []string {
+		fake := fftest.NewExecutor()
+		fake.On("-version").Stdout("ffmpeg version " + version + " Copyright (c) 2000-2021 the FFmpeg developers\n")
+		fake.On() // 能力检测
+		tool := &ffmpeg.FFmpegTool{Executor: fake}
+
+		cmd, err := ffmpeg.PresetDASH("in.mp4", ffmpeg.DASHOptions{
+			OutputDir:    "out",
+			Renditions:   []ffmpeg.Rendition{{Height: 720, VideoBitrate: "3000k"}},
+			HLSPlaylists: hls,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hls && !strings.Contains(strings.Join(cmd.Args(), " "), "-hls_master_name master.m3u8") {
+			t.Fatalf("args = %q, want -hls_master_name", cmd.Args())
+		}
+		ds, err := tool.Validate(context.Background(), cmd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []string
+		for _, d := range ds {
+			if d.Code == ffmpeg.DiagRequirement {
+				out = append(out, d.Message)
+			}
+		}
+		return out
+	}
+
+	if ds := requirementDiags("4.3.2", false); len(ds) != 0 {
+		t.Errorf("DASH only on 4.3: %q", ds)
+	}
+	if ds := requirementDiags("4.3.2", true); len(ds) != 1 || !strings.Contains(ds[0], "hls_master_name") {
+		t.Errorf("-hls_master_name on 4.3: %q, want one requirement diagnostic", ds)
+	}
+	if ds := requirementDiags("4.4", true); len(ds) != 0 {
+		t.Errorf("-hls_master_name on 4.4: %q", ds)
+	}
+	if ds := requirementDiags("4.1.6", false); len(ds) != 1 {
+		t.Errorf("DASH on 4.1: %q, want one requirement diagnostic", ds)
+	}
+}
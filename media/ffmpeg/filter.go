@@ -0,0 +1,231 @@
+package ffmpeg
+
+import (
+	"strings"
+)
+
+// Filter 表示 filtergraph 中的一个滤镜，例如 scale=1280:-2 或 [0:v][1:v]overlay=10:10[out]
+type Filter struct {
+	Name    string
+	Args    []string       // 位置参数，按 ":" 拼接
+	Options []FilterOption // key=value 参数，保持调用顺序
+	Inputs  []string       // 输入 pad 标签（不带方括号）
+	Outputs []string       // 输出 pad 标签（不带方括号）
+
+	raw string // RawFilter：原样输出，不做转义
+}
+
+type FilterOption struct {
+	Key   string
+	Value string
+}
+
+func NewFilter(name string, args ...string) *Filter {
+	return &Filter{Name: name, Args: args}
+}
+
+// RawFilter 原样插入一段已转义好的滤镜描述（可带标签），用于 builder 覆盖不到的场景
+func RawFilter(expr string) *Filter {
+	return &Filter{raw: expr}
+}
+
+func (f *Filter) Arg(v string) *Filter {
+	f.Args = append(f.Args, v)
+	return f
+}
+
+func (f *Filter) Opt(key, value string) *Filter {
+	f.Options = append(f.Options, FilterOption{Key: key, Value: value})
+	return f
+}
+
+func (f *Filter) In(labels ...string) *Filter {
+	for _, l := range labels {
+		f.Inputs = append(f.Inputs, trimLabel(l))
+	}
+	return f
+}
+
+func (f *Filter) Out(labels ...string) *Filter {
+	for _, l := range labels {
+		f.Outputs = append(f.Outputs, trimLabel(l))
+	}
+	return f
+}
+
+func (f *Filter) String() string {
+	var b strings.Builder
+	for _, l := range f.Inputs {
+		b.WriteString("[" + l + "]")
+	}
+	b.WriteString(f.body())
+	for _, l := range f.Outputs {
+		b.WriteString("[" + l + "]")
+	}
+	return b.String()
+}
+
+func (f *Filter) body() string {
+	if f.raw != "" {
+		return f.raw
+	}
+	parts := make([]string, 0, len(f.Args)+len(f.Options))
+	for _, a := range f.Args {
+		parts = append(parts, EscapeFilterValue(a))
+	}
+	for _, o := range f.Options {
+		parts = append(parts, o.Key+"="+EscapeFilterValue(o.Value))
+	}
+	if len(parts) == 0 {
+		return f.Name
+	}
+	// 第二层转义：filtergraph 级别的 [ ] , ; 等
+	return escapeFilterGraph(f.Name + "=" + strings.Join(parts, ":"))
+}
+
+// FilterChain 是用 "," 串起来的一组滤镜，对应 -vf / -af 或 filter_complex 中的一条链
+type FilterChain struct {
+	filters []*Filter
+}
+
+func NewFilterChain(filters ...*Filter) *FilterChain {
+	return &FilterChain{filters: filters}
+}
+
+func (c *FilterChain) Add(filters ...*Filter) *FilterChain {
+	c.filters = append(c.filters, filters...)
+	return c
+}
+
+func (c *FilterChain) Len() int {
+	if c == nil {
+		return 0
+	}
+	return len(c.filters)
+}
+
+func (c *FilterChain) Filters() []*Filter {
+	out := make([]*Filter, len(c.filters))
+	copy(out, c.filters)
+	return out
+}
+
+func (c *FilterChain) String() string {
+	if c == nil {
+		return ""
+	}
+	parts := make([]string, 0, len(c.filters))
+	for _, f := range c.filters {
+		parts = append(parts, f.String())
+	}
+	return strings.Join(parts, ",")
+}
+
+// FilterGraph 是用 ";" 分隔的多条链，对应 -filter_complex，链之间用具名 pad 连接
+type FilterGraph struct {
+	chains []*FilterChain
+}
+
+func NewFilterGraph() *FilterGraph {
+	return &FilterGraph{}
+}
+
+// Chain 新增一条链并返回它，便于继续 Add
+func (g *FilterGraph) Chain(filters ...*Filter) *FilterChain {
+	c := NewFilterChain(filters...)
+	g.chains = append(g.chains, c)
+	return c
+}
+
+func (g *FilterGraph) Chains() []*FilterChain {
+	out := make([]*FilterChain, len(g.chains))
+	copy(out, g.chains)
+	return out
+}
+
+func (g *FilterGraph) Empty() bool {
+	if g == nil {
+		return true
+	}
+	for _, c := range g.chains {
+		if c.Len() > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *FilterGraph) String() string {
+	if g == nil {
+		return ""
+	}
+	parts := make([]string, 0, len(g.chains))
+	for _, c := range g.chains {
+		if c.Len() == 0 {
+			continue
+		}
+		parts = append(parts, c.String())
+	}
+	return strings.Join(parts, ";")
+}
+
+// EscapeFilterValue 对单个滤镜参数做第一层转义（\ ' :）
+// 见 ffmpeg-filters 文档 "Notes on filtergraph escaping"
+func EscapeFilterValue(v string) string {
+	return escapeChars(v, `\':`)
+}
+
+// EscapeFilterGraphValue 对要写进 filtergraph 字符串（-vf / -filter_complex）的参数值做两层转义
+func EscapeFilterGraphValue(v string) string {
+	return escapeFilterGraph(EscapeFilterValue(v))
+}
+
+func escapeFilterGraph(s string) string {
+	return escapeChars(s, `\'[],;`)
+}
+
+func escapeChars(s, special string) string {
+	if !strings.ContainsAny(s, special) {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s) + 8)
+	for _, r := range s {
+		if strings.ContainsRune(special, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func trimLabel(l string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(l, "["), "]")
+}
+
+// 常用滤镜
+
+func ScaleFilter(w, h int) *Filter {
+	return NewFilter("scale", itoa(w), itoa(h))
+}
+
+func CropFilter(w, h, x, y string) *Filter {
+	return NewFilter("crop", w, h, x, y)
+}
+
+func PadFilter(w, h, x, y, color string) *Filter {
+	f := NewFilter("pad", w, h, x, y)
+	if color != "" {
+		f.Opt("color", color)
+	}
+	return f
+}
+
+func FPSFilter(fps string) *Filter {
+	return NewFilter("fps", fps)
+}
+
+// OverlayFilter 需要两个输入，一般用在 filter_complex：[0:v][1:v]overlay=x:y[out]
+func OverlayFilter(x, y string) *Filter {
+	return NewFilter("overlay", x, y)
+}
@@ -0,0 +1,240 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type HLSSegmentType string
+
+const (
+	HLSSegmentTS   HLSSegmentType = "mpegts"
+	HLSSegmentFMP4 HLSSegmentType = "fmp4"
+)
+
+// Rendition 一路码率档位，HLS / DASH 共用
+type Rendition struct {
+	Name   string // HLS 子目录名 / var_stream_map 的 name，默认 "<height>p"
+	Width  int    // <=0 表示按比例（-2）
+	Height int    // <=0 表示按比例（-2）
+
+	VideoCodec   string // 默认 libx264
+	VideoBitrate string // 例如 "3000k"
+	MaxRate      string // 可选，默认等于 VideoBitrate
+	BufSize      string // 可选，默认 2 倍 VideoBitrate
+
+	AudioCodec   string // 默认 aac
+	AudioBitrate string // 默认 128k
+}
+
+type HLSOptions struct {
+	OutputDir  string // 必填：master 和各档位子目录都写在这里
+	MasterName string // 默认 master.m3u8
+
+	Renditions []Rendition
+
+	SegmentDuration float64        // 默认 6 秒
+	SegmentType     HLSSegmentType // 默认 mpegts
+	// KeyframeAlign 按分片时长强制关键帧并关闭场景切换插帧，保证各档位分片边界一致
+	KeyframeAlign bool
+
+	Preset  string // x264 preset，默认 medium
+	NoAudio bool   // 输入没有音轨时设置
+}
+
+func (o HLSOptions) masterName() string {
+	if o.MasterName == "" {
+		return "master.m3u8"
+	}
+	return o.MasterName
+}
+
+// MasterPath 返回 master playlist 的完整路径
+func (o HLSOptions) MasterPath() string {
+	return filepath.Join(o.OutputDir, o.masterName())
+}
+
+func (r Rendition) name(i int) string {
+	if r.Name != "" {
+		return r.Name
+	}
+	if r.Height > 0 {
+		return itoa(r.Height) + "p"
+	}
+	return "v" + itoa(i)
+}
+
+// PresetHLS 一次 ffmpeg 输出多档位 HLS（VOD）：
+// split 出 N 路视频分别缩放编码，用 -var_stream_map 生成各自的 index.m3u8 和 master playlist
+func PresetHLS(input string, opt HLSOptions) (*FFmpegCommand, error) {
+	if opt.OutputDir == "" {
+		return nil, errors.New("hls: OutputDir is required")
+	}
+	n := len(opt.Renditions)
+	if n == 0 {
+		return nil, errors.New("hls: at least one rendition is required")
+	}
+	segDur := opt.SegmentDuration
+	if segDur <= 0 {
+		segDur = 6
+	}
+	segType := opt.SegmentType
+	if segType == "" {
+		segType = HLSSegmentTS
+	}
+	preset := opt.Preset
+	if preset == "" {
+		preset = "medium"
+	}
+
+	cmd := NewFFmpegCommand().
+		HideBanner().
+		LogLevel("error").
+		RequireVersion("4.0", "hls -var_stream_map").
+		Input(input)
+
+	out := cmd.AddOutput(filepath.Join(opt.OutputDir, "%v", "index.m3u8"))
+	names, err := mapRenditionVideo(cmd, out, opt.Renditions)
+	if err != nil {
+		return nil, fmt.Errorf("hls: %w", err)
+	}
+	streamMap := make([]string, 0, n)
+	for i, r := range opt.Renditions {
+		idx := itoa(i)
+		entry := "v:" + idx
+		if !opt.NoAudio {
+			acodec := r.AudioCodec
+			if acodec == "" {
+				acodec = "aac"
+			}
+			abitrate := r.AudioBitrate
+			if abitrate == "" {
+				abitrate = "128k"
+			}
+			out.Map("0:a:0").
+				Option("-c:a:"+idx, acodec, "-b:a:"+idx, abitrate)
+			entry += ",a:" + idx
+		}
+		streamMap = append(streamMap, entry+",name:"+names[i])
+	}
+
+	out.Option("-preset", preset)
+	if opt.KeyframeAlign {
+		alignKeyframes(out, segDur)
+	}
+
+	segExt := ".ts"
+	if segType == HLSSegmentFMP4 {
+		segExt = ".m4s"
+	}
+	out.Format("hls").
+		Option("-hls_time", trimFloat(segDur),
+			"-hls_playlist_type", "vod",
+			"-hls_flags", "independent_segments",
+			"-hls_segment_type", string(segType))
+	if segType == HLSSegmentFMP4 {
+		out.Option("-hls_fmp4_init_filename", "init.mp4")
+	}
+	out.Option("-hls_segment_filename", filepath.Join(opt.OutputDir, "%v", "seg_%05d"+segExt),
+		"-master_pl_name", opt.masterName(),
+		"-var_stream_map", strings.Join(streamMap, " "))
+
+	return cmd, nil
+}
+
+// PackageHLS 创建输出目录后执行 PresetHLS，进度通过 RunWithProgress 回调
+func (t *FFmpegTool) PackageHLS(
+	ctx context.Context,
+	input string,
+	opt HLSOptions,
+	onProgress func(p FFmpegProgress) error,
+) (FFmpegProgress, error) {
+	cmd, err := PresetHLS(input, opt)
+	if err != nil {
+		return FFmpegProgress{}, err
+	}
+	for i, r := range opt.Renditions {
+		if err := os.MkdirAll(filepath.Join(opt.OutputDir, r.name(i)), 0o755); err != nil {
+			return FFmpegProgress{}, fmt.Errorf("hls: create output dir: %w", err)
+		}
+	}
+	return t.RunWithProgress(ctx, cmd, onProgress)
+}
+
+// mapRenditionVideo 在 filter_complex 里 split + scale 出每一档视频，
+// 并在 out 上按档位序号映射、设置 -c:v:N / -b:v:N 等，返回各档名字
+func mapRenditionVideo(cmd *FFmpegCommand, out *OutputSpec, renditions []Rendition) ([]string, error) {
+	n := len(renditions)
+	g := cmd.FilterGraph()
+	split := NewFilter("split", itoa(n)).In("0:v")
+	for i := 0; i < n; i++ {
+		split.Out("v" + itoa(i))
+	}
+	g.Chain(split)
+
+	names := make([]string, 0, n)
+	seen := map[string]bool{}
+	for i, r := range renditions {
+		name := r.name(i)
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate rendition name %q", name)
+		}
+		seen[name] = true
+		if r.VideoBitrate == "" {
+			return nil, fmt.Errorf("rendition %q has no VideoBitrate", name)
+		}
+		names = append(names, name)
+
+		idx := itoa(i)
+		g.Chain(NewFilter("scale", dimOrAuto(r.Width), dimOrAuto(r.Height)).
+			In("v" + idx).
+			Out("v" + idx + "out"))
+
+		vcodec := r.VideoCodec
+		if vcodec == "" {
+			vcodec = "libx264"
+		}
+		maxRate := r.MaxRate
+		if maxRate == "" {
+			maxRate = r.VideoBitrate
+		}
+		bufSize := r.BufSize
+		if bufSize == "" {
+			bufSize = doubleBitrate(r.VideoBitrate)
+		}
+		out.MapLabel("v"+idx+"out").
+			Option("-c:v:"+idx, vcodec,
+				"-b:v:"+idx, r.VideoBitrate,
+				"-maxrate:v:"+idx, maxRate,
+				"-bufsize:v:"+idx, bufSize)
+	}
+	return names, nil
+}
+
+// alignKeyframes 每 segDur 秒强制一个关键帧并关闭场景切换插帧，各档位分片边界才能对齐
+func alignKeyframes(out *OutputSpec, segDur float64) {
+	out.Option("-sc_threshold", "0",
+		"-force_key_frames", "expr:gte(t,n_forced*"+trimFloat(segDur)+")")
+}
+
+func dimOrAuto(v int) string {
+	if v <= 0 {
+		return "-2"
+	}
+	return itoa(v)
+}
+
+// doubleBitrate "3000k" => "6000k"，无法解析时原样返回
+func doubleBitrate(b string) string {
+	num := strings.TrimRightFunc(b, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil || n <= 0 {
+		return b
+	}
+	return trimFloat(n*2) + b[len(num):]
+}
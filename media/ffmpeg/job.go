@@ -0,0 +1,242 @@
+package ffmpeg
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/LingByte/LingConvert/media/proc"
+)
+
+// ErrJobFinished Pause / Resume 时进程已经退出
+var ErrJobFinished = errors.New("ffmpeg: job already finished")
+
+// FFmpegJob 是 Start 返回的运行中任务
+type FFmpegJob struct {
+	proc   proc.Process
+	args   []string
+	cancel context.CancelCauseFunc
+	clock  *activeClock
+	done   chan struct{}
+
+	// mu 保护 paused / last；回调在锁外调用，回调里可以直接 Pause / Resume
+	mu     sync.Mutex
+	paused bool
+	last   FFmpegProgress
+	total  time.Duration
+	cb     func(p FFmpegProgress) error
+
+	// done 关闭后才可读
+	stderr string
+	err    error
+	result *RunResult
+}
+
+// RunResult 一次执行的结果和资源消耗，便于统计每个任务的编码成本
+type RunResult struct {
+	Args     []string // 完整命令行（含可执行文件）
+	ExitCode int      // 被信号杀掉为 -1
+	Progress FFmpegProgress
+	Stderr   string // stderr 末尾（FFmpegTool.StderrLimit）
+
+	StartedAt  time.Time
+	WallTime   time.Duration // 含暂停时间
+	UserTime   time.Duration
+	SystemTime time.Duration
+	MaxRSS     int64 // 峰值常驻内存，字节；平台不支持时为 0
+}
+
+// CPUTime 用户态 + 内核态 CPU 时间
+func (r *RunResult) CPUTime() time.Duration { return r.UserTime + r.SystemTime }
+
+// Wait 等待进程结束，返回最后一次进度
+func (j *FFmpegJob) Wait() (FFmpegProgress, error) {
+	<-j.done
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.last, j.err
+}
+
+// Result 等待进程结束并返回执行结果；失败时结果同样有效（退出码、stderr 等）
+func (j *FFmpegJob) Result() (*RunResult, error) {
+	last, err := j.Wait()
+	r := *j.result
+	r.Progress = last
+	return &r, err
+}
+
+// Done 进程结束（并且输出读取完毕）时关闭
+func (j *FFmpegJob) Done() <-chan struct{} { return j.done }
+
+// Stderr 返回 stderr 末尾；Wait 返回后才完整
+func (j *FFmpegJob) Stderr() string {
+	select {
+	case <-j.done:
+		return j.stderr
+	default:
+		return ""
+	}
+}
+
+// Args 实际执行的命令行（第一个元素是 ffmpeg 路径）
+func (j *FFmpegJob) Args() []string { return j.args }
+
+// Paused 当前是否处于暂停状态
+func (j *FFmpegJob) Paused() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.paused
+}
+
+// Pause 用 SIGSTOP 暂停 ffmpeg（有独立进程组时暂停整个组）。
+// 暂停期间不计入 FFmpegTool.Timeout；进度回调会收到一次 Paused=true 的进度。
+// 不支持的平台返回 errors.ErrUnsupported。
+func (j *FFmpegJob) Pause() error {
+	return j.setPaused(true)
+}
+
+// Resume 用 SIGCONT 恢复暂停的任务
+func (j *FFmpegJob) Resume() error {
+	return j.setPaused(false)
+}
+
+func (j *FFmpegJob) setPaused(pause bool) error {
+	j.mu.Lock()
+	select {
+	case <-j.done:
+		j.mu.Unlock()
+		return ErrJobFinished
+	default:
+	}
+	if j.paused == pause {
+		j.mu.Unlock()
+		return nil
+	}
+	if err := signalPause(j.proc, pause); err != nil {
+		j.mu.Unlock()
+		return err
+	}
+	j.paused = pause
+	if pause {
+		j.clock.pause()
+	} else {
+		j.clock.resume()
+	}
+	j.last.Paused = pause
+	p := j.last
+	j.mu.Unlock()
+
+	if j.cb != nil {
+		if err := j.cb(p); err != nil {
+			j.cancel(context.Canceled)
+		}
+	}
+	return nil
+}
+
+// continueIfPaused 停止前调用：暂停中的进程收不到 q / SIGINT
+func (j *FFmpegJob) continueIfPaused() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.paused {
+		if signalPause(j.proc, false) == nil {
+			j.paused = false
+		}
+	}
+}
+
+func (j *FFmpegJob) scanProgress(r io.Reader) {
+	// progress 输出是一行一个 key=value
+	// 使用 bufio.Scanner 足够；如果你担心超长行，可自定义 SplitFunc
+	sc := bufio.NewScanner(r)
+	var p FFmpegProgress
+	for sc.Scan() {
+		parseProgressLine(sc.Text(), &p)
+
+		// ETA 按实际运行时间估算，不含暂停
+		p.estimate(j.total, j.clock.active())
+		j.mu.Lock()
+		p.Paused = j.paused
+		j.last = p
+		j.mu.Unlock()
+		if j.cb != nil {
+			if err := j.cb(p); err != nil {
+				// 业务想中止
+				j.cancel(context.Canceled)
+				return
+			}
+		}
+		if p.Done {
+			return
+		}
+	}
+}
+
+// activeClock 记录不含暂停的运行时间，并在累计达到 limit 时调用 onExpire（limit<=0 不超时）
+type activeClock struct {
+	mu       sync.Mutex
+	limit    time.Duration
+	onExpire func()
+	timer    *time.Timer
+	since    time.Time     // 本段运行的开始时间，暂停时为零值
+	elapsed  time.Duration // 之前各段运行时间之和
+	stopped  bool
+}
+
+func newActiveClock(limit time.Duration, onExpire func()) *activeClock {
+	c := &activeClock{limit: limit, onExpire: onExpire, since: time.Now()}
+	if limit > 0 {
+		c.timer = time.AfterFunc(limit, onExpire)
+	}
+	return c
+}
+
+func (c *activeClock) active() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.since.IsZero() {
+		return c.elapsed
+	}
+	return c.elapsed + time.Since(c.since)
+}
+
+func (c *activeClock) pause() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.since.IsZero() || c.stopped {
+		return
+	}
+	c.elapsed += time.Since(c.since)
+	c.since = time.Time{}
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+}
+
+func (c *activeClock) resume() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.since.IsZero() || c.stopped {
+		return
+	}
+	c.since = time.Now()
+	if c.timer != nil {
+		remain := c.limit - c.elapsed
+		if remain < 0 {
+			remain = 0
+		}
+		c.timer.Reset(remain)
+	}
+}
+
+func (c *activeClock) stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stopped = true
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+}
@@ -0,0 +1,102 @@
+package ffmpeg
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// commandJSON 是 FFmpegCommand 的存储格式，字段顺序固定，滤镜保存为渲染后的字符串。
+// 改动格式时递增 commandJSONVersion，解码时保持兼容
+type commandJSON struct {
+	Version       int               `json:"version"`
+	Global        []string          `json:"global,omitempty"`
+	Inputs        []inputJSON       `json:"inputs,omitempty"`
+	FilterComplex string            `json:"filter_complex,omitempty"`
+	Outputs       []outputJSON      `json:"outputs,omitempty"`
+	Trailing      []string          `json:"trailing,omitempty"` // 没有被 Input/Output 收走的选项
+	VideoFilter   string            `json:"vf,omitempty"`
+	AudioFilter   string            `json:"af,omitempty"`
+	Requires      []requirementJSON `json:"requires,omitempty"`
+}
+
+type inputJSON struct {
+	Path    string   `json:"path"`
+	Options []string `json:"options,omitempty"`
+}
+
+type outputJSON struct {
+	Path        string   `json:"path"`
+	Options     []string `json:"options,omitempty"`
+	VideoFilter string   `json:"vf,omitempty"`
+	AudioFilter string   `json:"af,omitempty"`
+}
+
+type requirementJSON struct {
+	MinVersion string `json:"min_version,omitempty"`
+	Library    string `json:"library,omitempty"`
+	MinLibrary string `json:"min_library,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+const commandJSONVersion = 1
+
+// MarshalJSON 稳定编码：同一个命令总是得到相同的字节，解码后 Args() 完全一致
+func (c *FFmpegCommand) MarshalJSON() ([]byte, error) {
+	v := commandJSON{
+		Version:       commandJSONVersion,
+		Global:        c.global,
+		FilterComplex: c.graph.String(),
+		Trailing:      c.pending,
+		VideoFilter:   c.vf.String(),
+		AudioFilter:   c.af.String(),
+	}
+	for _, in := range c.inputs {
+		v.Inputs = append(v.Inputs, inputJSON{Path: in.Path, Options: in.opts})
+	}
+	for _, o := range c.outputs {
+		v.Outputs = append(v.Outputs, outputJSON{Path: o.Path, Options: o.opts, VideoFilter: o.vf.String(), AudioFilter: o.af.String()})
+	}
+	for _, r := range c.requires {
+		v.Requires = append(v.Requires, requirementJSON(r))
+	}
+	return json.Marshal(v)
+}
+
+func (c *FFmpegCommand) UnmarshalJSON(data []byte) error {
+	var v commandJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if v.Version > commandJSONVersion {
+		return fmt.Errorf("ffmpeg: unsupported command encoding version %d", v.Version)
+	}
+	n := FFmpegCommand{
+		global:  v.Global,
+		pending: v.Trailing,
+		vf:      rawChain(v.VideoFilter),
+		af:      rawChain(v.AudioFilter),
+	}
+	if v.FilterComplex != "" {
+		n.FilterGraph().Chain(RawFilter(v.FilterComplex))
+	}
+	for _, in := range v.Inputs {
+		n.AddInput(in.Path).opts = in.Options
+	}
+	for _, o := range v.Outputs {
+		spec := n.AddOutput(o.Path)
+		spec.opts = o.Options
+		spec.vf, spec.af = rawChain(o.VideoFilter), rawChain(o.AudioFilter)
+	}
+	for _, r := range v.Requires {
+		n.requires = append(n.requires, Requirement(r))
+	}
+	*c = n
+	return nil
+}
+
+func rawChain(s string) *FilterChain {
+	if s == "" {
+		return nil
+	}
+	return NewFilterChain(RawFilter(s))
+}
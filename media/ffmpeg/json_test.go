@@ -0,0 +1,67 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestCommandJSONRoundTrip(t *testing.T) {
+	cmd := NewFFmpegCommand().HideBanner().LogLevel("error").
+		RequireVersion("5.0", "fps_mode").
+		RequireLibrary("libass", "", "subtitles filter")
+	cmd.AddInput("in.mp4").Seek(1.5)
+	cmd.AddInput("logo.png").Option("-loop", "1")
+	g := cmd.FilterGraph()
+	g.Chain(OverlayFilter("10", "10").In("0:v", "1:v"), NewFilter("drawtext").Opt("text", "a, b: it's [x]").Out("v"))
+	g.Chain(NewFilter("anull").In("0:a").Out("a"))
+	cmd.AddOutput("out.mp4").MapLabel("v").MapLabel("a").VideoCodec("libx264").Option("-crf", "20")
+	cmd.AddOutput("thumb.jpg").Map("0:v:0").VideoFilter(ScaleFilter(320, -2)).Option("-frames:v", "1")
+	cmd.AddOutput("audio.m4a").Map("0:a:0").AudioFilter(NewFilter("volume", "0.5"))
+
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got FFmpegCommand
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Args(), cmd.Args()) {
+		t.Errorf("Args() after round trip:\n got %q\nwant %q", got.Args(), cmd.Args())
+	}
+	if !reflect.DeepEqual(got.Requirements(), cmd.Requirements()) {
+		t.Errorf("Requirements() = %+v, want %+v", got.Requirements(), cmd.Requirements())
+	}
+	again, err := json.Marshal(&got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again, data) {
+		t.Errorf("encoding is not stable:\n%s\n%s", data, again)
+	}
+}
+
+func TestCommandJSONLegacyChain(t *testing.T) {
+	cmd := NewFFmpegCommand().AppendArgs("-ss", "3").Input("in.mp4").Scale(640, -2).CRF(23).Output("out.mp4").AppendArgs("-t", "5")
+
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got FFmpegCommand
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Args(), cmd.Args()) {
+		t.Errorf("Args() after round trip:\n got %q\nwant %q", got.Args(), cmd.Args())
+	}
+}
+
+func TestCommandJSONVersion(t *testing.T) {
+	var c FFmpegCommand
+	if err := json.Unmarshal([]byte(`{"version":99}`), &c); err == nil {
+		t.Error("unknown version: want error")
+	}
+}
@@ -0,0 +1,210 @@
+package ffmpeg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// LoudnormOptions EBU R128 目标值及第二遍的输出参数。
+// TruePeak / LRA 的 0 是合法目标，所以用指针：nil 表示用默认值
+type LoudnormOptions struct {
+	IntegratedLUFS float64  // 目标响度 I，默认 -16（播客/流媒体）；广电常用 -23。合法范围 -70..-5，0 即默认
+	TruePeak       *float64 // 目标真峰值 TP (dBTP)，默认 -1.5
+	LRA            *float64 // 目标响度范围，默认 11
+
+	AudioCodec   string // 默认 aac
+	AudioBitrate string // 默认 192k
+	SampleRate   int    // loudnorm 内部会升采样到 192k，这里重新指定输出采样率，默认 48000
+	NoVideo      bool   // 输出不带视频；默认视频流 copy
+}
+
+func (o LoudnormOptions) withDefaults() LoudnormOptions {
+	if o.IntegratedLUFS == 0 {
+		o.IntegratedLUFS = -16
+	}
+	if o.TruePeak == nil {
+		tp := -1.5
+		o.TruePeak = &tp
+	}
+	if o.LRA == nil {
+		lra := 11.0
+		o.LRA = &lra
+	}
+	if o.AudioCodec == "" {
+		o.AudioCodec = "aac"
+	}
+	if o.AudioBitrate == "" {
+		o.AudioBitrate = "192k"
+	}
+	if o.SampleRate <= 0 {
+		o.SampleRate = 48000
+	}
+	return o
+}
+
+func (o LoudnormOptions) targetFilter() *Filter {
+	return NewFilter("loudnorm").
+		Opt("I", formatDB(o.IntegratedLUFS)).
+		Opt("TP", formatDB(*o.TruePeak)).
+		Opt("LRA", formatDB(*o.LRA))
+}
+
+// LoudnessMeasurement loudnorm print_format=json 的结果
+type LoudnessMeasurement struct {
+	InputI       float64 // LUFS
+	InputTP      float64 // dBTP
+	InputLRA     float64 // LU
+	InputThresh  float64 // LUFS
+	OutputI      float64
+	OutputTP     float64
+	OutputLRA    float64
+	OutputThresh float64
+	TargetOffset float64
+
+	NormalizationType string // "dynamic" / "linear"
+}
+
+type loudnormJSON struct {
+	InputI            string `json:"input_i"`
+	InputTP           string `json:"input_tp"`
+	InputLRA          string `json:"input_lra"`
+	InputThresh       string `json:"input_thresh"`
+	OutputI           string `json:"output_i"`
+	OutputTP          string `json:"output_tp"`
+	OutputLRA         string `json:"output_lra"`
+	OutputThresh      string `json:"output_thresh"`
+	NormalizationType string `json:"normalization_type"`
+	TargetOffset      string `json:"target_offset"`
+}
+
+// MeasureLoudness 第一遍：loudnorm 只分析不输出，解析 stderr 里的 JSON
+func (t *FFmpegTool) MeasureLoudness(
+	ctx context.Context,
+	input string,
+	opt LoudnormOptions,
+	onProgress func(p FFmpegProgress) error,
+) (*LoudnessMeasurement, error) {
+	return t.measureLoudness(ctx, input, opt.withDefaults(), onProgress)
+}
+
+func (t *FFmpegTool) measureLoudness(
+	ctx context.Context,
+	input string,
+	opt LoudnormOptions,
+	onProgress func(p FFmpegProgress) error,
+) (*LoudnessMeasurement, error) {
+	// JSON 以 info 级别打印，不能用 -v error
+	cmd := NewFFmpegCommand().
+		HideBanner().
+		LogLevel("info").
+		Input(input).
+		AppendArgs("-vn", "-sn", "-dn").
+		AudioFilter(opt.targetFilter().Opt("print_format", "json")).
+		AppendArgs("-f", "null").
+		Output("-")
+
+	_, stderr, err := t.run(ctx, cmd, RunOptions{OnProgress: onProgress})
+	if err != nil {
+		return nil, err
+	}
+	return parseLoudnormJSON(stderr)
+}
+
+// NormalizeLoudness 两遍 loudnorm：先测量，再用测量值做线性归一化写到 output
+func (t *FFmpegTool) NormalizeLoudness(
+	ctx context.Context,
+	input, output string,
+	opt LoudnormOptions,
+	onProgress func(p FFmpegProgress) error,
+) (*LoudnessMeasurement, error) {
+	opt = opt.withDefaults()
+	m, err := t.measureLoudness(ctx, input, opt, passProgress(1, 2, onProgress))
+	if err != nil {
+		return nil, fmt.Errorf("loudnorm pass 1: %w", err)
+	}
+	if math.IsInf(m.InputI, 0) || math.IsInf(m.InputThresh, 0) {
+		return m, errors.New("loudnorm: input is silent, nothing to normalize")
+	}
+
+	f := opt.targetFilter().
+		Opt("measured_I", formatDB(m.InputI)).
+		Opt("measured_TP", formatDB(m.InputTP)).
+		Opt("measured_LRA", formatDB(m.InputLRA)).
+		Opt("measured_thresh", formatDB(m.InputThresh)).
+		Opt("offset", formatDB(m.TargetOffset)).
+		Opt("linear", "true").
+		Opt("print_format", "summary")
+
+	cmd := NewFFmpegCommand().
+		HideBanner().
+		LogLevel("error").
+		Input(input)
+	if opt.NoVideo {
+		cmd.AppendArgs("-vn")
+	} else {
+		cmd.Map("0:v?").CopyVideo()
+	}
+	cmd.Map("0:a:0").
+		AudioFilter(f).
+		AudioCodec(opt.AudioCodec).
+		AppendArgs("-b:a", opt.AudioBitrate, "-ar", itoa(opt.SampleRate)).
+		Output(output)
+
+	if _, _, err := t.run(ctx, cmd, RunOptions{OnProgress: passProgress(2, 2, onProgress)}); err != nil {
+		return m, fmt.Errorf("loudnorm pass 2: %w", err)
+	}
+	return m, nil
+}
+
+// parseLoudnormJSON 从 stderr 里找最后一段包含 input_i 的 {...}
+func parseLoudnormJSON(stderr string) (*LoudnessMeasurement, error) {
+	end := strings.LastIndex(stderr, "}")
+	if end < 0 {
+		return nil, errors.New("loudnorm: no JSON found in ffmpeg output")
+	}
+	start := strings.LastIndex(stderr[:end], "{")
+	if start < 0 {
+		return nil, errors.New("loudnorm: no JSON found in ffmpeg output")
+	}
+	var raw loudnormJSON
+	if err := json.Unmarshal([]byte(stderr[start:end+1]), &raw); err != nil {
+		return nil, fmt.Errorf("loudnorm: parse JSON: %w", err)
+	}
+	if raw.InputI == "" {
+		return nil, errors.New("loudnorm: JSON has no input_i")
+	}
+
+	var perr error
+	num := func(s string) float64 {
+		f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil && perr == nil {
+			perr = fmt.Errorf("loudnorm: bad value %q: %w", s, err)
+		}
+		return f
+	}
+	m := &LoudnessMeasurement{
+		InputI:            num(raw.InputI),
+		InputTP:           num(raw.InputTP),
+		InputLRA:          num(raw.InputLRA),
+		InputThresh:       num(raw.InputThresh),
+		OutputI:           num(raw.OutputI),
+		OutputTP:          num(raw.OutputTP),
+		OutputLRA:         num(raw.OutputLRA),
+		OutputThresh:      num(raw.OutputThresh),
+		TargetOffset:      num(raw.TargetOffset),
+		NormalizationType: raw.NormalizationType,
+	}
+	if perr != nil {
+		return nil, perr
+	}
+	return m, nil
+}
+
+func formatDB(f float64) string {
+	return strconv.FormatFloat(f, 'f', 2, 64)
+}
@@ -0,0 +1,107 @@
+package ffmpeg_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/LingByte/LingConvert/media/ffmpeg"
+	fftest "github.com/LingByte/LingConvert/media/testing"
+)
+
+// ffmpeg 6.1 第一遍（-af loudnorm=...:print_format=json -f null -）的 stderr 原样截取
+const loudnormPass1Stderr = `Input #0, mov,mp4,m4a,3gp,3g2,mj2, from 'in.mp4':
+  Metadata:
+    major_brand     : isom
+  Duration: 00:01:00.02, start: 0.000000, bitrate: 1187 kb/s
+  Stream #0:1[0x2](und): Audio: aac (LC) (mp4a / 0x6134706D), 48000 Hz, stereo, fltp, 128 kb/s (default)
+Stream mapping:
+  Stream #0:1 -> #0:0 (aac (native) -> pcm_s16le (native))
+Output #0, null, to 'pipe:':
+  Stream #0:0(und): Audio: pcm_s16le, 192000 Hz, stereo, s16, 6144 kb/s (default)
+[Parsed_loudnorm_0 @ 0x55d5c8f0a2c0]
+{
+	"input_i" : "-27.61",
+	"input_tp" : "-4.47",
+	"input_lra" : "18.06",
+	"input_thresh" : "-39.20",
+	"output_i" : "-16.58",
+	"output_tp" : "-1.50",
+	"output_lra" : "14.78",
+	"output_thresh" : "-27.71",
+	"normalization_type" : "dynamic",
+	"target_offset" : "0.58"
+}
+[out#0/null @ 0x55d5c8f05b40] video:0kB audio:45000kB subtitle:0kB other streams:0kB global headers:0kB muxing overhead: unknown
+size=N/A time=00:01:00.00 bitrate=N/A speed= 112x
+`
+
+func TestMeasureLoudness(t *testing.T) {
+	fake := fftest.NewExecutor()
+	fake.On("print_format=json").Stderr(loudnormPass1Stderr)
+	tool := &ffmpeg.FFmpegTool{Executor: fake}
+
+	m, err := tool.MeasureLoudness(context.Background(), "in.mp4", ffmpeg.LoudnormOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ffmpeg.LoudnessMeasurement{
+		InputI: -27.61, InputTP: -4.47, InputLRA: 18.06, InputThresh: -39.20,
+		OutputI: -16.58, OutputTP: -1.50, OutputLRA: 14.78, OutputThresh: -27.71,
+		TargetOffset: 0.58, NormalizationType: "dynamic",
+	}
+	if *m != want {
+		t.Errorf("measurement = %+v, want %+v", *m, want)
+	}
+
+	runs := ffmpegRuns(fake)
+	if len(runs) != 1 || !strings.Contains(runs[0], " loudnorm=I=-16.00:TP=-1.50:LRA=11.00:print_format=json ") {
+		t.Errorf("pass 1 = %q", runs)
+	}
+}
+
+func TestNormalizeLoudnessPass2(t *testing.T) {
+	fake := fftest.NewExecutor()
+	fake.On("print_format=json").Stderr(loudnormPass1Stderr)
+	fake.On("-i") // 第二遍
+	tool := &ffmpeg.FFmpegTool{Executor: fake}
+
+	tp, lra := 0.0, 7.0
+	opt := ffmpeg.LoudnormOptions{IntegratedLUFS: -23, TruePeak: &tp, LRA: &lra, NoVideo: true}
+	if _, err := tool.NormalizeLoudness(context.Background(), "in.mp4", "out.m4a", opt, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	runs := ffmpegRuns(fake)
+	if len(runs) != 2 {
+		t.Fatalf("runs = %q", runs)
+	}
+	if !strings.Contains(runs[0], " loudnorm=I=-23.00:TP=0.00:LRA=7.00:print_format=json ") {
+		t.Errorf("pass 1 = %q", runs[0])
+	}
+	wantFilter := " loudnorm=I=-23.00:TP=0.00:LRA=7.00" +
+		":measured_I=-27.61:measured_TP=-4.47:measured_LRA=18.06:measured_thresh=-39.20" +
+		":offset=0.58:linear=true:print_format=summary "
+	if !strings.Contains(runs[1], wantFilter) {
+		t.Errorf("pass 2 = %q, want filter %q", runs[1], wantFilter)
+	}
+	for _, want := range []string{" -vn ", " -map 0:a:0 ", " -c:a aac ", " -b:a 192k ", " -ar 48000 ", " out.m4a "} {
+		if !strings.Contains(runs[1], want) {
+			t.Errorf("pass 2 = %q, missing %q", runs[1], want)
+		}
+	}
+}
+
+func TestNormalizeLoudnessSilent(t *testing.T) {
+	silent := strings.NewReplacer(`"-27.61"`, `"-inf"`, `"-39.20"`, `"-inf"`).Replace(loudnormPass1Stderr)
+	fake := fftest.NewExecutor()
+	fake.On("print_format=json").Stderr(silent)
+	tool := &ffmpeg.FFmpegTool{Executor: fake}
+
+	if _, err := tool.NormalizeLoudness(context.Background(), "in.mp4", "out.mp4", ffmpeg.LoudnormOptions{}, nil); err == nil {
+		t.Fatal("silent input: want error")
+	}
+	if runs := ffmpegRuns(fake); len(runs) != 1 {
+		t.Errorf("silent input should stop after pass 1, runs = %q", runs)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package ffmpeg
+
+import (
+	"errors"
+
+	"github.com/LingByte/LingConvert/media/proc"
+)
+
+func signalPause(p proc.Process, pause bool) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package ffmpeg
+
+import (
+	"syscall"
+
+	"github.com/LingByte/LingConvert/media/proc"
+)
+
+// signalPause 发送 SIGSTOP / SIGCONT；ffmpeg 是进程组组长时发给整个组
+func signalPause(p proc.Process, pause bool) error {
+	if pause {
+		return p.Signal(syscall.SIGSTOP)
+	}
+	return p.Signal(syscall.SIGCONT)
+}
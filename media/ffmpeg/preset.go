@@ -13,6 +13,7 @@ func PresetTranscodeMP4H264AAC(input, output string, crf int, preset string) *FF
 	return NewFFmpegCommand().
 		HideBanner().
 		LogLevel("error").
+		RequireLibrary("libx264", "", "H.264 encoding").
 		Input(input).
 		VideoCodec("libx264").
 		AudioCodec("aac").
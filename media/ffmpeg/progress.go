@@ -3,20 +3,39 @@ package ffmpeg
 import (
 	"strconv"
 	"strings"
+	"time"
 )
 
 type FFmpegProgress struct {
-	Frame     int
-	FPS       float64
-	Bitrate   string
-	Speed     string
+	Frame   int
+	FPS     float64
+	Bitrate string
+	Speed   float64 // "1.5x" => 1.5；ffmpeg 输出 N/A 时为 0
+	// 注意：ffmpeg 的 out_time_ms 实际单位是微秒，这里原样保留；用 OutTime 更直观
 	OutTimeMs int64
+	OutTime   time.Duration // 来自 out_time_us / out_time
+
+	TotalSize  int64 // 已写出的字节数
+	DupFrames  int
+	DropFrames int
+
+	// 已知总时长时才有值（RunOptions.TotalDuration 或 FFmpegTool.Prober 探测）
+	Percent float64       // 0~100；多遍处理时为总体进度
+	ETA     time.Duration // 当前这一遍的预计剩余时间
 
 	// ffmpeg 会输出 progress=continue / progress=end
 	Done bool
+	// 任务被 FFmpegJob.Pause 暂停中
+	Paused bool
+
+	// 多遍处理（loudnorm、两遍编码）时 Pass 为当前遍数（从 1 开始），Passes 为总遍数；单遍均为 0
+	Pass   int
+	Passes int
 
 	// 保留未知字段，便于排障/扩展
 	Extra map[string]string
+
+	exactOutTime bool // 当前块已有 out_time_us / out_time_ms
 }
 
 func (p *FFmpegProgress) applyKV(k, v string) {
@@ -32,13 +51,41 @@ func (p *FFmpegProgress) applyKV(k, v string) {
 	case "bitrate":
 		p.Bitrate = v
 	case "speed":
-		p.Speed = v
+		if f, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(v), "x"), 64); err == nil {
+			p.Speed = f
+		}
 	case "out_time_ms":
+		// 名字是 ms，实际单位是微秒
 		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
 			p.OutTimeMs = n
+			if n >= 0 {
+				p.OutTime, p.exactOutTime = time.Duration(n)*time.Microsecond, true
+			}
+		}
+	case "out_time_us":
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n >= 0 {
+			p.OutTime, p.exactOutTime = time.Duration(n)*time.Microsecond, true
+		}
+	case "out_time":
+		// 只有 out_time 的老版本每块都靠它更新；同一块里有微秒值时以微秒值为准
+		if d, ok := parseClock(v); ok && !p.exactOutTime {
+			p.OutTime = d
+		}
+	case "total_size":
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			p.TotalSize = n
+		}
+	case "dup_frames":
+		if n, err := strconv.Atoi(v); err == nil {
+			p.DupFrames = n
+		}
+	case "drop_frames":
+		if n, err := strconv.Atoi(v); err == nil {
+			p.DropFrames = n
 		}
 	case "progress":
 		p.Done = (v == "end")
+		p.exactOutTime = false
 	default:
 		if p.Extra == nil {
 			p.Extra = map[string]string{}
@@ -60,3 +107,60 @@ func parseProgressLine(line string, p *FFmpegProgress) {
 	v := line[i+1:]
 	p.applyKV(k, v)
 }
+
+// estimate 根据总时长和已用时间计算 Percent / ETA
+func (p *FFmpegProgress) estimate(total, elapsed time.Duration) {
+	if total <= 0 {
+		return
+	}
+	if p.Done {
+		p.Percent, p.ETA = 100, 0
+		return
+	}
+	done := p.OutTime
+	if done <= 0 {
+		return
+	}
+	if done > total {
+		done = total
+	}
+	p.Percent = float64(done) / float64(total) * 100
+	remain := total - done
+	switch {
+	case p.Speed > 0:
+		p.ETA = time.Duration(float64(remain) / p.Speed)
+	case elapsed > 0:
+		p.ETA = time.Duration(float64(elapsed) * float64(remain) / float64(done))
+	}
+}
+
+// parseClock 解析 "HH:MM:SS.micro"，ffmpeg 可能输出负数或 N/A
+func parseClock(s string) (time.Duration, bool) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) != 3 || strings.HasPrefix(s, "-") {
+		return 0, false
+	}
+	h, err1 := strconv.Atoi(parts[0])
+	m, err2 := strconv.Atoi(parts[1])
+	sec, err3 := strconv.ParseFloat(parts[2], 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return 0, false
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute +
+		time.Duration(sec*float64(time.Second)), true
+}
+
+// passProgress 给回调的进度打上遍数，并把单遍的 Percent 换算成总体进度
+func passProgress(pass, passes int, cb func(p FFmpegProgress) error) func(p FFmpegProgress) error {
+	if cb == nil {
+		return nil
+	}
+	return func(p FFmpegProgress) error {
+		p.Pass = pass
+		p.Passes = passes
+		if passes > 0 {
+			p.Percent = (float64(pass-1)*100 + p.Percent) / float64(passes)
+		}
+		return cb(p)
+	}
+}
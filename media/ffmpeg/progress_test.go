@@ -0,0 +1,44 @@
+package ffmpeg
+
+import (
+	"testing"
+	"time"
+)
+
+func feedProgress(p *FFmpegProgress, lines ...string) {
+	for _, l := range lines {
+		parseProgressLine(l, p)
+	}
+}
+
+func TestProgressOutTimeOnly(t *testing.T) {
+	// 没有 out_time_us 的版本：每块都要从 out_time 更新
+	var p FFmpegProgress
+	feedProgress(&p, "frame=25", "out_time=00:00:01.000000", "progress=continue")
+	if p.OutTime != time.Second {
+		t.Fatalf("OutTime = %s, want 1s", p.OutTime)
+	}
+	feedProgress(&p, "frame=50", "out_time=00:00:02.500000", "progress=end")
+	if p.OutTime != 2500*time.Millisecond || !p.Done {
+		t.Errorf("OutTime = %s, Done = %v, want 2.5s done", p.OutTime, p.Done)
+	}
+}
+
+func TestProgressMicrosecondsWin(t *testing.T) {
+	var p FFmpegProgress
+	// out_time 被取整到微秒以下会有误差，同一块里以 out_time_us 为准
+	feedProgress(&p, "out_time_us=1234567", "out_time_ms=1234567", "out_time=00:00:01.230000", "progress=continue")
+	if p.OutTime != 1234567*time.Microsecond || p.OutTimeMs != 1234567 {
+		t.Fatalf("OutTime = %s, OutTimeMs = %d", p.OutTime, p.OutTimeMs)
+	}
+	// out_time_us 为 N/A 的块退回 out_time
+	feedProgress(&p, "out_time_us=N/A", "out_time_ms=N/A", "out_time=00:00:03.000000", "progress=continue")
+	if p.OutTime != 3*time.Second {
+		t.Errorf("OutTime = %s, want 3s", p.OutTime)
+	}
+	var q FFmpegProgress
+	feedProgress(&q, "out_time_ms=2000000", "out_time=00:00:01.000000", "progress=continue")
+	if q.OutTime != 2*time.Second {
+		t.Errorf("out_time_ms only: OutTime = %s, want 2s", q.OutTime)
+	}
+}
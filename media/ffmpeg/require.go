@@ -0,0 +1,132 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/LingByte/LingConvert/media/locate"
+)
+
+// 版本相关类型见 locate 包
+type (
+	Version    = locate.Version
+	LibVersion = locate.LibVersion
+	BuildInfo  = locate.BuildInfo
+)
+
+// ParseVersion 解析 "6.1.1-static"、"N-112345-gabcdef0" 等版本号
+func ParseVersion(s string) Version { return locate.ParseVersion(s) }
+
+// Requirement 命令对 ffmpeg 版本或库的要求，在启动进程前检查
+type Requirement struct {
+	MinVersion string // 最低 ffmpeg 版本，例如 "6.1"
+	Library    string // libav* 库（"libavfilter"）或外部库（"libx264"，对应 --enable-libx264）
+	MinLibrary string // Library 是 libav* 时的最低版本，例如 "9.12"
+	Reason     string // 写进错误信息，说明哪个功能需要它
+}
+
+// ErrRequirement 已安装的 ffmpeg 不满足命令声明的要求，errors.As 到 *RequirementError 取详情
+var ErrRequirement = errors.New("ffmpeg requirement not met")
+
+type RequirementError struct {
+	Requirement
+	Have string // 实际的 ffmpeg / 库版本，库缺失时为空
+}
+
+func (e *RequirementError) Error() string {
+	var msg string
+	switch {
+	case e.MinVersion != "":
+		msg = fmt.Sprintf("ffmpeg %s is too old, need >= %s", e.Have, e.MinVersion)
+	case e.Have == "":
+		msg = fmt.Sprintf("ffmpeg is built without %s", e.Library)
+	default:
+		msg = fmt.Sprintf("ffmpeg %s %s is too old, need >= %s", e.Library, e.Have, e.MinLibrary)
+	}
+	if e.Reason != "" {
+		msg += " (" + e.Reason + ")"
+	}
+	return msg
+}
+
+func (e *RequirementError) Unwrap() error { return ErrRequirement }
+
+// Require 声明命令的运行要求
+func (c *FFmpegCommand) Require(r Requirement) *FFmpegCommand {
+	c.requires = append(c.requires, r)
+	return c
+}
+
+// RequireVersion 要求 ffmpeg 不低于 min
+func (c *FFmpegCommand) RequireVersion(min, reason string) *FFmpegCommand {
+	return c.Require(Requirement{MinVersion: min, Reason: reason})
+}
+
+// RequireLibrary 要求编译了某个库；min 为空只检查存在
+func (c *FFmpegCommand) RequireLibrary(name, min, reason string) *FFmpegCommand {
+	return c.Require(Requirement{Library: name, MinLibrary: min, Reason: reason})
+}
+
+func (c *FFmpegCommand) Requirements() []Requirement {
+	out := make([]Requirement, len(c.requires))
+	copy(out, c.requires)
+	return out
+}
+
+// BuildInfo 返回 ffmpeg 的版本、编译配置和 libav* 库版本
+func (t *FFmpegTool) BuildInfo(ctx context.Context) (*BuildInfo, error) {
+	return t.locator().BuildInfo(ctx)
+}
+
+// CheckRequirements 检查命令声明的全部要求，返回所有不满足项（errors.Join）
+func (t *FFmpegTool) CheckRequirements(ctx context.Context, cmd *FFmpegCommand) error {
+	if len(cmd.requires) == 0 {
+		return nil
+	}
+	info, err := t.BuildInfo(ctx)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, r := range cmd.requires {
+		if err := checkRequirement(info, r); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func checkRequirement(info *BuildInfo, r Requirement) error {
+	if r.MinVersion != "" {
+		min := locate.ParseVersion(r.MinVersion)
+		if min.Kind != locate.VersionRelease {
+			return fmt.Errorf("ffmpeg: invalid MinVersion %q", r.MinVersion)
+		}
+		if !info.AtLeast(min) {
+			return &RequirementError{Requirement: Requirement{MinVersion: r.MinVersion, Reason: r.Reason}, Have: info.Version.String()}
+		}
+	}
+	if r.Library == "" {
+		return nil
+	}
+	if !info.HasLibrary(r.Library) {
+		return &RequirementError{Requirement: Requirement{Library: r.Library, MinLibrary: r.MinLibrary, Reason: r.Reason}}
+	}
+	if r.MinLibrary == "" {
+		return nil
+	}
+	have, ok := info.Libraries[r.Library]
+	if !ok {
+		// 外部库（--enable-xxx）没有版本信息
+		return nil
+	}
+	min, err := locate.ParseLibVersion(r.MinLibrary)
+	if err != nil {
+		return fmt.Errorf("ffmpeg: %w", err)
+	}
+	if have.Compare(min) < 0 {
+		return &RequirementError{Requirement: Requirement{Library: r.Library, MinLibrary: r.MinLibrary, Reason: r.Reason}, Have: have.String()}
+	}
+	return nil
+}
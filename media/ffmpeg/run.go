@@ -1,15 +1,18 @@
 package ffmpeg
 
 import (
-	"bufio"
-	"bytes"
 	"context"
-	"errors"
 	"fmt"
 	"io"
-	"os/exec"
+	"os"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/LingByte/LingConvert/media/fferr"
+	"github.com/LingByte/LingConvert/media/proc"
 )
 
 func (t *FFmpegTool) Run(ctx context.Context, cmd *FFmpegCommand) error {
@@ -20,122 +23,346 @@ func (t *FFmpegTool) Run(ctx context.Context, cmd *FFmpegCommand) error {
 // RunWithProgress:
 // - 若 onProgress != nil，会自动追加：-progress pipe:1 -nostats
 // - 进度从 stdout 读；stderr 保留给错误信息
+// - 设置了 FFmpegTool.Prober 时会探测输入时长，进度里带 Percent / ETA
 func (t *FFmpegTool) RunWithProgress(
 	ctx context.Context,
 	cmd *FFmpegCommand,
 	onProgress func(p FFmpegProgress) error,
 ) (FFmpegProgress, error) {
-	var last FFmpegProgress
+	return t.RunWithOptions(ctx, cmd, RunOptions{OnProgress: onProgress})
+}
+
+// RunOptions 单次执行的可选参数
+type RunOptions struct {
+	OnProgress func(p FFmpegProgress) error
+
+	// TotalDuration 输出的预期总时长，用于计算 Percent / ETA；
+	// 为 0 时若 FFmpegTool.Prober 非 nil，则探测第一个输入并结合 -ss/-t 推算
+	TotalDuration time.Duration
+
+	// Stdin 作为 pipe:0 输入（见 FFmpegCommand.PipeInput）
+	Stdin io.Reader
+	// Stdout 接收 pipe:1 输出（见 FFmpegCommand.PipeOutput）；
+	// 此时进度改走 fd 3（-progress pipe:3），不会和媒体数据混在一起。
+	// 写入阻塞时 ffmpeg 也会阻塞，天然背压。
+	Stdout io.Writer
+}
+
+// streamWaitDelay 流式执行时，进程结束/取消后最多再等这么久让 Stdin/Stdout 的拷贝收尾，
+// 避免 Stdin 的 Read 一直阻塞导致 Wait 卡住
+const streamWaitDelay = 5 * time.Second
+
+func (t *FFmpegTool) RunWithOptions(ctx context.Context, cmd *FFmpegCommand, opt RunOptions) (FFmpegProgress, error) {
+	last, _, err := t.run(ctx, cmd, opt)
+	return last, err
+}
+
+// RunWithResult 同 RunWithOptions，返回包含耗时、CPU、内存、退出码的 RunResult；
+// 进程启动后即使失败也会返回 RunResult
+func (t *FFmpegTool) RunWithResult(ctx context.Context, cmd *FFmpegCommand, opt RunOptions) (*RunResult, error) {
+	job, err := t.Start(ctx, cmd, opt)
+	if err != nil {
+		return nil, err
+	}
+	return job.Result()
+}
+
+// run 同 RunWithOptions，另外返回 stderr，给需要解析 ffmpeg 日志的功能用（例如 loudnorm）
+func (t *FFmpegTool) run(
+	ctx context.Context,
+	cmd *FFmpegCommand,
+	opt RunOptions,
+) (FFmpegProgress, string, error) {
+	job, err := t.Start(ctx, cmd, opt)
+	if err != nil {
+		return FFmpegProgress{}, "", err
+	}
+	last, err := job.Wait()
+	return last, job.Stderr(), err
+}
 
-	if err := t.ensureReady(ctx); err != nil {
-		return last, err
+// Start 启动 ffmpeg 后立即返回，用返回的 FFmpegJob 等待、暂停或恢复
+func (t *FFmpegTool) Start(ctx context.Context, cmd *FFmpegCommand, opt RunOptions) (*FFmpegJob, error) {
+	bin, err := t.binary(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// 版本/库不满足时在启动前失败，而不是让 ffmpeg 报一个看不懂的选项错误
+	if err := t.CheckRequirements(ctx, cmd); err != nil {
+		return nil, err
 	}
 
-	timeout := t.Timeout
-	var cctx context.Context
-	var cancel context.CancelFunc
-	if timeout > 0 {
-		cctx, cancel = context.WithTimeout(ctx, timeout)
-	} else {
-		cctx, cancel = context.WithCancel(ctx)
+	// 超时按"实际运行时间"计算，暂停期间不计入，所以不用 context.WithTimeout
+	cctx, cancel := context.WithCancelCause(ctx)
+	job := &FFmpegJob{
+		cb:     opt.OnProgress,
+		done:   make(chan struct{}),
+		cancel: cancel,
 	}
-	defer cancel()
+	job.clock = newActiveClock(t.Timeout, func() { cancel(context.DeadlineExceeded) })
+	started := false
+	defer func() {
+		if !started {
+			job.clock.stop()
+			cancel(nil)
+		}
+	}()
 
-	t.mu.Lock()
-	bin := t.resolvedPath
-	t.mu.Unlock()
+	onProgress := opt.OnProgress
+	if onProgress != nil {
+		job.total = opt.TotalDuration
+		if job.total <= 0 && t.Prober != nil {
+			job.total = t.probeTotalDuration(cctx, cmd)
+		}
+	}
 
-	args := cmd.Args()
+	progressURL := "pipe:1"
+	if opt.Stdout != nil {
+		progressURL = "pipe:3"
+	}
+	args := cmd.args(t.Limits.Threads)
 	if onProgress != nil {
 		// ffmpeg progress is key=value lines
-		args = append(args, "-progress", "pipe:1", "-nostats")
+		args = append(args, "-progress", progressURL, "-nostats")
 	}
+	job.args = append([]string{bin}, args...)
 
-	execCmd := exec.CommandContext(cctx, bin, args...)
+	spec := proc.Spec{Path: bin, Args: args, Limits: t.Limits}
+	if opt.Stdin != nil || opt.Stdout != nil {
+		spec.Stdin = opt.Stdin
+		spec.WaitDelay = streamWaitDelay
+	}
 
-	stdout, err := execCmd.StdoutPipe()
-	if err != nil {
-		return last, fmt.Errorf("ffmpeg stdout pipe: %w", err)
+	// 管道自己建，进程退出后还能读完剩余输出。writers 是交给子进程的一端，Wait 之后才关。
+	// progressSrc 是进度来源：普通模式为 stdout，流式输出模式为 fd 3
+	var progressSrc, stderrSrc, quitW *os.File
+	var writers []*os.File
+	closeAll := func(fs ...*os.File) {
+		for _, f := range fs {
+			if f != nil {
+				_ = f.Close()
+			}
+		}
+	}
+	if opt.Stdout == nil || onProgress != nil {
+		pr, pw, err := os.Pipe()
+		if err != nil {
+			return nil, fmt.Errorf("ffmpeg progress pipe: %w", err)
+		}
+		progressSrc = pr
+		writers = append(writers, pw)
+		if opt.Stdout != nil {
+			spec.ExtraFiles = []*os.File{pw} // 子进程里是 fd 3
+		} else {
+			spec.Stdout = pw
+		}
+	}
+	if opt.Stdout != nil {
+		spec.Stdout = opt.Stdout
 	}
-	stderr, err := execCmd.StderrPipe()
+	sr, sw, err := os.Pipe()
 	if err != nil {
-		return last, fmt.Errorf("ffmpeg stderr pipe: %w", err)
+		closeAll(progressSrc)
+		closeAll(writers...)
+		return nil, fmt.Errorf("ffmpeg stderr pipe: %w", err)
 	}
+	stderrSrc = sr
+	writers = append(writers, sw)
+	spec.Stderr = sw
 
-	if err := execCmd.Start(); err != nil {
-		return last, fmt.Errorf("ffmpeg start: %w", err)
+	stopRequested := new(atomic.Bool)
+	if mode := t.stopMode(opt); mode != StopKill {
+		if mode == StopQuit {
+			qr, qw, err := os.Pipe()
+			if err != nil {
+				closeAll(progressSrc, stderrSrc)
+				closeAll(writers...)
+				return nil, fmt.Errorf("ffmpeg stdin pipe: %w", err)
+			}
+			spec.Stdin = qr
+			writers = append(writers, qr)
+			quitW = qw
+		}
+		spec.Cancel = func(p proc.Process) error {
+			// 暂停中的进程收不到 q / SIGINT，停止前先恢复
+			job.continueIfPaused()
+			stopRequested.Store(true)
+			return gracefulStop(p, quitW)
+		}
+		grace := t.StopGrace
+		if grace <= 0 {
+			grace = DefaultStopGrace
+		}
+		if grace > spec.WaitDelay {
+			spec.WaitDelay = grace
+		}
 	}
 
-	var stderrBuf bytes.Buffer
+	startedAt := time.Now()
+	p, startErr := t.executor().Start(cctx, spec)
+	if startErr != nil {
+		closeAll(writers...)
+		closeAll(progressSrc, stderrSrc, quitW)
+		return nil, fmt.Errorf("ffmpeg start: %w", startErr)
+	}
+	job.proc = p
+	started = true
+
+	// stderr 只保留末尾，逐行交给 OnLog / Logger
+	stderrBuf := newStderrSink(t.StderrLimit, t.logHook(ctx))
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		_, _ = io.Copy(&stderrBuf, stderr)
+		_, _ = io.Copy(stderrBuf, stderrSrc)
+		stderrBuf.flush()
 	}()
 
-	if onProgress != nil {
-		// parse progress from stdout
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			scanProgress(stdout, &last, onProgress, cancel)
-		}()
-	} else {
-		// 不需要 progress，就把 stdout 消耗掉，避免管道堵塞
+	if progressSrc != nil {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			_, _ = io.Copy(io.Discard, stdout)
+			if onProgress != nil {
+				job.scanProgress(progressSrc)
+			}
+			// 不需要 progress 或已提前返回，把剩余输出消耗掉，避免管道堵塞
+			_, _ = io.Copy(io.Discard, progressSrc)
 		}()
 	}
 
-	waitErr := execCmd.Wait()
-	wg.Wait()
+	go func() {
+		defer close(job.done)
+		defer cancel(nil)
+		defer job.clock.stop()
+
+		st, waitErr := p.Wait()
+		// 进程退出后再关掉父进程持有的子进程端（Executor 不一定复制了它们），读端随后读到 EOF
+		closeAll(writers...)
+		wg.Wait()
+		closeAll(progressSrc, stderrSrc, quitW)
 
-	if waitErr != nil {
-		// context timeout / cancel
-		if errors.Is(cctx.Err(), context.DeadlineExceeded) {
-			return last, fmt.Errorf("ffmpeg timed out after %s; stderr=%s", timeout, trimSpace(stderrBuf.String()))
+		job.stderr = stderrBuf.String()
+		job.result = &RunResult{
+			Args:      job.args,
+			ExitCode:  -1,
+			Stderr:    job.stderr,
+			StartedAt: startedAt,
+			WallTime:  time.Since(startedAt),
 		}
-		if errors.Is(cctx.Err(), context.Canceled) && onProgress != nil && last.Done {
-			// 正常完成也可能 cancel？一般不会，保守不走这里
+		if st != nil {
+			job.result.ExitCode = st.ExitCode
+			job.result.UserTime = st.UserTime
+			job.result.SystemTime = st.SystemTime
+			job.result.MaxRSS = st.MaxRSS
 		}
-		// exit error
-		var ee *exec.ExitError
-		if errors.As(waitErr, &ee) {
-			return last, fmt.Errorf("ffmpeg failed: %w; stderr=%s", waitErr, trimSpace(stderrBuf.String()))
+		if waitErr != nil {
+			// 超时/取消优先，其余按 stderr 归类；errors.As 到 *ffmpeg.Error 可取详情
+			cause := context.Cause(cctx)
+			e := fferr.New("ffmpeg", job.args, job.stderr, waitErr, cause)
+			if stopRequested.Load() && st != nil && st.Exited {
+				// 优雅停止后 ffmpeg 自己退出（没等到被杀），输出已收尾；Err 统一为取消/超时原因
+				e.Kind = fferr.ErrStoppedEarly
+				e.ExitCode = st.ExitCode
+				e.Err = cause
+			}
+			job.err = e
 		}
-		return last, fmt.Errorf("ffmpeg exec error: %w; stderr=%s", waitErr, trimSpace(stderrBuf.String()))
+	}()
+
+	return job, nil
+}
+
+// RunStream 从 r 读取输入、把输出写到 w。
+// 命令需要用 PipeInput / PipeOutput 声明 pipe:0 / pipe:1；r 或 w 可以为 nil（只流式化一端）
+func (t *FFmpegTool) RunStream(
+	ctx context.Context,
+	cmd *FFmpegCommand,
+	r io.Reader,
+	w io.Writer,
+	onProgress func(p FFmpegProgress) error,
+) (FFmpegProgress, error) {
+	return t.RunWithOptions(ctx, cmd, RunOptions{OnProgress: onProgress, Stdin: r, Stdout: w})
+}
+
+// probeTotalDuration 探测第一个输入的时长，再按 -ss / -t 推算输出时长；探测失败返回 0
+func (t *FFmpegTool) probeTotalDuration(ctx context.Context, cmd *FFmpegCommand) time.Duration {
+	if len(cmd.inputs) == 0 {
+		return 0
+	}
+	in := cmd.inputs[0]
+	if in.Path == "-" || strings.HasPrefix(in.Path, "pipe:") {
+		return 0
+	}
+	info, err := t.Prober.Probe(ctx, in.Path)
+	if err != nil {
+		return 0
+	}
+	secs, err := strconv.ParseFloat(info.Format.Duration, 64)
+	if err != nil || secs <= 0 {
+		return 0
 	}
+	total := time.Duration(secs * float64(time.Second))
 
-	return last, nil
+	// 输入侧 -ss 跳过开头，-t 截断
+	total = applySeekLimit(total, in.opts)
+	for _, o := range cmd.outputs {
+		total = applySeekLimit(total, o.opts)
+	}
+	return total
 }
 
-func scanProgress(r io.Reader, last *FFmpegProgress, cb func(p FFmpegProgress) error, cancel context.CancelFunc) {
-	// progress 输出是一行一个 key=value
-	// 使用 bufio.Scanner 足够；如果你担心超长行，可自定义 SplitFunc
-	sc := bufio.NewScanner(r)
-	var p FFmpegProgress
-	for sc.Scan() {
-		line := sc.Text()
-		parseProgressLine(line, &p)
-		*last = p
-		if cb != nil {
-			if err := cb(p); err != nil {
-				// 业务想中止
-				cancel()
-				return
-			}
+func applySeekLimit(total time.Duration, opts []string) time.Duration {
+	var ss, limit time.Duration
+	for i := 0; i+1 < len(opts); i++ {
+		switch opts[i] {
+		case "-ss":
+			ss = parseTimeArg(opts[i+1])
+		case "-t":
+			limit = parseTimeArg(opts[i+1])
 		}
-		if p.Done {
-			return
+	}
+	if ss > 0 {
+		total -= ss
+		if total < 0 {
+			total = 0
 		}
 	}
+	if limit > 0 && limit < total {
+		total = limit
+	}
+	return total
 }
 
-func trimSpace(s string) string {
-	s = strings.TrimSpace(s)
-	// stderr 可能很长，你也可以在这里做截断策略（例如最多 64KB）
-	return s
+// parseTimeArg 支持秒数 "12.5" 和 "HH:MM:SS.xx"
+func parseTimeArg(s string) time.Duration {
+	if d, ok := parseClock(s); ok {
+		return d
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f < 0 {
+		return 0
+	}
+	return time.Duration(f * float64(time.Second))
+}
+
+// stopMode 实际使用的停止方式：用了 RunOptions.Stdin 时 stdin 不归我们，q 退化为 SIGINT
+func (t *FFmpegTool) stopMode(opt RunOptions) StopMode {
+	if t.StopMode == StopQuit && opt.Stdin != nil {
+		return StopInterrupt
+	}
+	return t.StopMode
+}
+
+// gracefulStop 替换默认的 kill：先让 ffmpeg 自己退出（q / SIGINT）以写完 moov 等尾部数据，
+// StopGrace（Spec.WaitDelay）后仍未退出由 Executor 强杀
+func gracefulStop(p proc.Process, quit io.Writer) error {
+	if quit != nil {
+		if _, err := io.WriteString(quit, "q"); err == nil {
+			return nil
+		}
+	}
+	if err := p.Signal(os.Interrupt); err == nil {
+		return nil
+	}
+	return p.Signal(os.Kill)
 }
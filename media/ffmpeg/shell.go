@@ -0,0 +1,222 @@
+package ffmpeg
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// ShellQuote 按 POSIX sh 规则给参数加引号，只在需要时加；结果在 bash 和 zsh 里也能原样粘贴
+func ShellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	safe := s[0] != '=' // zsh 会把开头的 =cmd 展开成命令路径
+	for _, r := range s {
+		if !isShellSafe(r) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// isShellSafe 不需要引号的字符；^ 在 zsh extendedglob 下是通配符，不算
+func isShellSafe(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return true
+	}
+	return strings.ContainsRune("-_./:=+,@%", r)
+}
+
+// ShellJoin 把参数拼成可以直接粘贴到终端的命令行
+func ShellJoin(args ...string) string {
+	parts := make([]string, len(args))
+	for i, a := range args {
+		parts[i] = ShellQuote(a)
+	}
+	return strings.Join(parts, " ")
+}
+
+// CommandLine 渲染成 shell 命令行；bin 为空时用 "ffmpeg"
+func (c *FFmpegCommand) CommandLine(bin string) string {
+	if bin == "" {
+		bin = "ffmpeg"
+	}
+	return ShellJoin(append([]string{bin}, c.Args()...)...)
+}
+
+// ShellSplit 按 sh 的规则切分命令行：支持单引号、双引号、反斜杠转义和 "\" 续行；
+// 不做变量展开、通配和命令替换
+func ShellSplit(s string) ([]string, error) {
+	var (
+		args   []string
+		cur    strings.Builder
+		inWord bool
+	)
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			if inWord {
+				args = append(args, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		case ch == '\\':
+			if i+1 >= len(s) {
+				return nil, errors.New("ffmpeg: trailing backslash in command line")
+			}
+			i++
+			if s[i] == '\n' {
+				continue // 续行
+			}
+			cur.WriteByte(s[i])
+			inWord = true
+		case ch == '\'':
+			j := strings.IndexByte(s[i+1:], '\'')
+			if j < 0 {
+				return nil, errors.New("ffmpeg: unterminated single quote in command line")
+			}
+			cur.WriteString(s[i+1 : i+1+j])
+			i += j + 1
+			inWord = true
+		case ch == '"':
+			i++
+			for ; i < len(s) && s[i] != '"'; i++ {
+				// 双引号里反斜杠只转义 $ ` " \ 和换行
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("$`\"\\\n", s[i+1]) >= 0 {
+					i++
+					if s[i] == '\n' {
+						continue
+					}
+				}
+				cur.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, errors.New("ffmpeg: unterminated double quote in command line")
+			}
+			inWord = true
+		default:
+			cur.WriteByte(ch)
+			inWord = true
+		}
+	}
+	if inWord {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
+
+// ParseCommandLine 把 shell 风格的 ffmpeg 命令行解析成 FFmpegCommand，开头的 ffmpeg 可执行文件可以省略。
+// 结果按 global / 输入 / -filter_complex / 输出 的规范顺序重新组织，Args() 与原命令语义等价
+func ParseCommandLine(s string) (*FFmpegCommand, error) {
+	args, err := ShellSplit(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) > 0 && isFFmpegBinary(args[0]) {
+		args = args[1:]
+	}
+	return ParseArgs(args)
+}
+
+func isFFmpegBinary(a string) bool {
+	base := strings.ToLower(filepath.Base(a))
+	return base == "ffmpeg" || base == "ffmpeg.exe"
+}
+
+// boolOptions ffmpeg 文档里不带值的选项（OPT_BOOL 和 -h/-version 这类信息选项），名字不含 "-"，
+// 值为是否全局选项。OPT_BOOL 选项都能加 no 前缀取反（-nostdin、-noautorotate）。
+// 不在表里的选项（包括编码器/封装器的 AVOption）在命令行上都带一个值
+var boolOptions = map[string]bool{
+	// 全局
+	"y": true, "n": true, "hide_banner": true, "stdin": true, "stats": true,
+	"benchmark": true, "benchmark_all": true, "report": true, "xerror": true,
+	"ignore_unknown": true, "copy_unknown": true, "recast_media": true, "copyts": true,
+	"start_at_zero": true, "dump": true, "hex": true, "debug_ts": true, "print_graphs": true,
+	"vstats": true, "qphist": true,
+	// 输入/输出
+	"vn": false, "an": false, "sn": false, "dn": false, "re": false, "shortest": false,
+	"accurate_seek": false, "autorotate": false, "autoscale": false, "find_stream_info": false,
+	"fix_sub_duration": false, "fix_sub_duration_heartbeat": false, "ignore_chapters": false,
+	"bitexact": false, "copyinkf": false, "copypriorss": false, "intra": false, "psnr": false,
+	// 信息
+	"h": true, "?": true, "help": true, "version": true, "buildconf": true, "L": true,
+	"formats": true, "muxers": true, "demuxers": true, "devices": true, "codecs": true,
+	"decoders": true, "encoders": true, "bsfs": true, "protocols": true, "filters": true,
+	"pix_fmts": true, "layouts": true, "sample_fmts": true, "dispositions": true,
+	"colors": true, "hwaccels": true,
+}
+
+// globalValueOptions 带值的全局选项，名字不含 "-"；位置无关，解析时统一放到最前面
+var globalValueOptions = map[string]bool{
+	"v": true, "loglevel": true, "progress": true, "stats_period": true,
+	"filter_threads": true, "filter_complex_threads": true, "filter_complex_script": true,
+	"max_error_rate": true, "abort_on": true, "init_hw_device": true, "filter_hw_device": true,
+	"sdp_file": true, "vstats_file": true, "vstats_version": true, "copytb": true, "timelimit": true,
+	"max_alloc": true, "cpuflags": true, "cpucount": true,
+}
+
+// optionArity 返回选项是否带值、是否全局；流说明符（-c:v 里的 ":v"）不影响判断
+func optionArity(opt string) (takesValue, global bool) {
+	name := strings.TrimPrefix(opt, "-")
+	if i := strings.IndexByte(name, ':'); i > 0 {
+		name = name[:i]
+	}
+	if g, ok := boolOptions[name]; ok {
+		return false, g
+	}
+	if base, ok := strings.CutPrefix(name, "no"); ok {
+		if g, ok := boolOptions[base]; ok {
+			return false, g
+		}
+	}
+	return true, globalValueOptions[name]
+}
+
+// ParseArgs 把 ffmpeg 参数（不含可执行文件）解析成 FFmpegCommand：
+// -i 之前的选项归该输入，不是选项的参数是输出路径，之前的选项归该输出；
+// 最后一个输出之后多余的选项保留在末尾
+func ParseArgs(args []string) (*FFmpegCommand, error) {
+	c := &FFmpegCommand{}
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if !strings.HasPrefix(a, "-") || a == "-" {
+			o := c.AddOutput(a)
+			o.opts, c.pending = c.pending, nil
+			continue
+		}
+		takesValue, global := optionArity(a)
+		if !takesValue {
+			if global {
+				c.global = append(c.global, a)
+			} else {
+				c.pending = append(c.pending, a)
+			}
+			continue
+		}
+		if i+1 >= len(args) {
+			return nil, fmt.Errorf("ffmpeg: missing argument for option %s", a)
+		}
+		v := args[i+1]
+		i++
+		switch {
+		case a == "-i":
+			in := c.AddInput(v)
+			in.opts, c.pending = c.pending, nil
+		case a == "-filter_complex" || a == "-lavfi":
+			c.FilterGraph().Chain(RawFilter(v))
+		case global:
+			c.global = append(c.global, a, v)
+		default:
+			c.pending = append(c.pending, a, v)
+		}
+	}
+	return c, nil
+}
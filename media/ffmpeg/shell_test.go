@@ -0,0 +1,167 @@
+package ffmpeg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestShellSplit(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+		err  bool
+	}{
+		{in: "", want: nil},
+		{in: "ffmpeg -i in.mp4 out.mp4", want: []string{"ffmpeg", "-i", "in.mp4", "out.mp4"}},
+		{in: "  a \t b\n", want: []string{"a", "b"}},
+		{in: `-i 'my file.mp4'`, want: []string{"-i", "my file.mp4"}},
+		{in: `'it'\''s.mp4'`, want: []string{"it's.mp4"}},
+		{in: `"a \"b\" \$c \x"`, want: []string{`a "b" $c \x`}},
+		{in: `a\ b`, want: []string{"a b"}},
+		{in: "-i in.mp4 \\\n out.mp4", want: []string{"-i", "in.mp4", "out.mp4"}},
+		{in: `''`, want: []string{""}},
+		{in: `x'y'"z"`, want: []string{"xyz"}},
+		{in: `'open`, err: true},
+		{in: `"open`, err: true},
+		{in: `trailing\`, err: true},
+	}
+	for _, tt := range tests {
+		got, err := ShellSplit(tt.in)
+		if tt.err {
+			if err == nil {
+				t.Errorf("ShellSplit(%q) = %q, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ShellSplit(%q) error: %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ShellSplit(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"", "''"},
+		{"out.mp4", "out.mp4"},
+		{"-c:v", "-c:v"},
+		{"+faststart", "+faststart"},
+		{"keyint=60:min-keyint=30", "keyint=60:min-keyint=30"},
+		{"my file.mp4", "'my file.mp4'"},
+		{"it's", `'it'\''s'`},
+		{"scale=1280:-2,fps=30", "scale=1280:-2,fps=30"},
+		{"[0:v]", "'[0:v]'"},
+		{"$HOME", "'$HOME'"},
+		{"*.mp4", "'*.mp4'"},
+		// zsh：extendedglob 下 ^ 是取反通配，开头的 = 会展开成命令路径
+		{"a^b", "'a^b'"},
+		{"=ffmpeg", "'=ffmpeg'"},
+	}
+	for _, tt := range tests {
+		if got := ShellQuote(tt.in); got != tt.want {
+			t.Errorf("ShellQuote(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+		if args, err := ShellSplit(ShellQuote(tt.in)); err != nil || len(args) != 1 || args[0] != tt.in {
+			t.Errorf("ShellSplit(ShellQuote(%q)) = %q, %v", tt.in, args, err)
+		}
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []string
+		want []string
+		err  bool
+	}{
+		{
+			name: "globals move to front",
+			in:   []string{"-i", "in.mp4", "-y", "-loglevel", "error", "out.mp4"},
+			want: []string{"-y", "-loglevel", "error", "-i", "in.mp4", "out.mp4"},
+		},
+		{
+			name: "input options stay with input",
+			in:   []string{"-ss", "5", "-re", "-i", "in.mp4", "-c", "copy", "out.mp4"},
+			want: []string{"-ss", "5", "-re", "-i", "in.mp4", "-c", "copy", "out.mp4"},
+		},
+		{
+			name: "boolean options do not take the next token",
+			in:   []string{"-i", "in.mp4", "-bitexact", "-copyinkf", "-dn", "-vn", "out.mp4"},
+			want: []string{"-i", "in.mp4", "-bitexact", "-copyinkf", "-dn", "-vn", "out.mp4"},
+		},
+		{
+			name: "no prefix",
+			in:   []string{"-nostdin", "-noautorotate", "-i", "in.mp4", "-nostats", "out.mp4"},
+			want: []string{"-nostdin", "-nostats", "-noautorotate", "-i", "in.mp4", "out.mp4"},
+		},
+		{
+			name: "unknown options take a value",
+			in:   []string{"-i", "in.mp4", "-movflags", "+faststart", "-x264-params", "keyint=60", "out.mp4"},
+			want: []string{"-i", "in.mp4", "-movflags", "+faststart", "-x264-params", "keyint=60", "out.mp4"},
+		},
+		{
+			name: "stream specifier",
+			in:   []string{"-i", "in.mp4", "-c:v", "libx264", "-b:a", "128k", "out.mp4"},
+			want: []string{"-i", "in.mp4", "-c:v", "libx264", "-b:a", "128k", "out.mp4"},
+		},
+		{
+			name: "filter_complex",
+			in:   []string{"-i", "a.mp4", "-i", "b.mp4", "-filter_complex", "[0:v][1:v]hstack", "out.mp4"},
+			want: []string{"-i", "a.mp4", "-i", "b.mp4", "-filter_complex", "[0:v][1:v]hstack", "out.mp4"},
+		},
+		{
+			name: "multiple outputs",
+			in:   []string{"-i", "in.mp4", "-an", "v.mp4", "-vn", "a.m4a"},
+			want: []string{"-i", "in.mp4", "-an", "v.mp4", "-vn", "a.m4a"},
+		},
+		{
+			name: "stdout output",
+			in:   []string{"-i", "in.mp4", "-f", "mpegts", "-"},
+			want: []string{"-i", "in.mp4", "-f", "mpegts", "-"},
+		},
+		{
+			name: "missing value",
+			in:   []string{"-i", "in.mp4", "-c:v"},
+			err:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, err := ParseArgs(tt.in)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("ParseArgs(%q) succeeded, want error", tt.in)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseArgs(%q) error: %v", tt.in, err)
+			}
+			if got := cmd.Args(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Args() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCommandLineRoundTrip(t *testing.T) {
+	line := `ffmpeg -y -ss 1.5 -i 'my clip.mp4' -vf 'scale=1280:-2,drawtext=text='\''hi'\''' -c:v libx264 -bitexact out.mp4`
+	cmd, err := ParseCommandLine(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := ParseCommandLine(cmd.CommandLine(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(again.Args(), cmd.Args()) {
+		t.Errorf("round trip = %q, want %q", again.Args(), cmd.Args())
+	}
+	want := []string{"-y", "-ss", "1.5", "-i", "my clip.mp4", "-vf", "scale=1280:-2,drawtext=text='hi'", "-c:v", "libx264", "-bitexact", "out.mp4"}
+	if got := cmd.Args(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Args() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,205 @@
+package ffmpeg
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type SplitMode int
+
+const (
+	// SplitCopy segment muxer + stream copy：不重新编码，只能在关键帧处切，片段时长会有偏差
+	SplitCopy SplitMode = iota
+	// SplitExact 重新编码，在切点强制插入关键帧，片段从准确的时间点开始
+	SplitExact
+)
+
+// SplitOptions 切分参数；SegmentTime 和 At 二选一
+type SplitOptions struct {
+	Mode SplitMode
+
+	SegmentTime time.Duration   // 按固定时长切
+	At          []time.Duration // 在这些时间点切（相对输入开头，升序）
+
+	// Pattern 输出文件名模板，带一个整数占位，例如 "out/part_%03d.mp4"；目录需已存在
+	Pattern     string
+	StartNumber int    // 第一个片段的序号，默认 0
+	Format      string // 片段封装格式，默认按 Pattern 扩展名推断
+
+	// 以下只用于 SplitExact
+	VideoCodec string // 默认 libx264
+	AudioCodec string // 默认 aac
+	CRF        int    // 默认 23
+	Preset     string // 默认 medium
+}
+
+// Segment 一个输出片段；Start 是片段在原始输入里的位置，来自 segment muxer 的列表，
+// Duration 由 ffprobe 读回实际文件得到
+type Segment struct {
+	Index    int
+	Path     string
+	Start    time.Duration
+	Duration time.Duration
+}
+
+func (o SplitOptions) check() error {
+	switch {
+	case o.Pattern == "":
+		return errors.New("split: Pattern is required")
+	case !strings.Contains(o.Pattern, "%"):
+		return fmt.Errorf("split: Pattern %q has no %%d placeholder", o.Pattern)
+	case o.SegmentTime > 0 && len(o.At) > 0:
+		return errors.New("split: set either SegmentTime or At, not both")
+	case o.SegmentTime <= 0 && len(o.At) == 0:
+		return errors.New("split: SegmentTime or At is required")
+	}
+	if !sort.SliceIsSorted(o.At, func(i, k int) bool { return o.At[i] < o.At[k] }) {
+		return errors.New("split: At must be in ascending order")
+	}
+	for _, at := range o.At {
+		if at <= 0 {
+			return fmt.Errorf("split: cut point %s must be positive", at)
+		}
+	}
+	return nil
+}
+
+// Split 把 input 切成多段，返回实际生成的片段（按顺序）。
+// 由 segment muxer 完成，片段列表写在私有临时目录里，结束后删除
+func (t *FFmpegTool) Split(
+	ctx context.Context,
+	input string,
+	opt SplitOptions,
+	onProgress func(p FFmpegProgress) error,
+) ([]Segment, error) {
+	if err := opt.check(); err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "ffsplit-*")
+	if err != nil {
+		return nil, fmt.Errorf("split: create list dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	list := filepath.Join(dir, "segments.csv")
+
+	cmd := splitCommand(input, list, opt)
+	if _, err := t.RunWithProgress(ctx, cmd, onProgress); err != nil {
+		return nil, fmt.Errorf("split: %w", err)
+	}
+	entries, err := readSegmentList(list)
+	if err != nil {
+		return nil, fmt.Errorf("split: %w", err)
+	}
+
+	prober := t.prober()
+	out := make([]Segment, 0, len(entries))
+	for i, e := range entries {
+		// 列表里只有文件名，目录和 Pattern 相同
+		path := filepath.Join(filepath.Dir(opt.Pattern), e.name)
+		info, err := prober.Probe(ctx, path)
+		if err != nil {
+			return out, fmt.Errorf("split: probe segment %s: %w", path, err)
+		}
+		out = append(out, Segment{Index: opt.StartNumber + i, Path: path, Start: e.start, Duration: probedDuration(info)})
+	}
+	return out, nil
+}
+
+func splitCommand(input, list string, opt SplitOptions) *FFmpegCommand {
+	cmd := NewFFmpegCommand().HideBanner().LogLevel("error")
+	cmd.AddInput(input)
+	o := cmd.AddOutput(opt.Pattern).Map("0:v?").Map("0:a?")
+
+	var times string
+	if len(opt.At) > 0 {
+		parts := make([]string, len(opt.At))
+		for i, at := range opt.At {
+			parts[i] = trimFloat(at.Seconds())
+		}
+		times = strings.Join(parts, ",")
+	}
+
+	if opt.Mode == SplitExact {
+		if opt.VideoCodec == "" {
+			opt.VideoCodec = "libx264"
+		}
+		if opt.AudioCodec == "" {
+			opt.AudioCodec = "aac"
+		}
+		if opt.CRF <= 0 {
+			opt.CRF = 23
+		}
+		if opt.Preset == "" {
+			opt.Preset = "medium"
+		}
+		// 切点处强制关键帧，segment muxer 就能准确地在这里切开
+		keyframes := times
+		if keyframes == "" {
+			keyframes = "expr:gte(t,n_forced*" + trimFloat(opt.SegmentTime.Seconds()) + ")"
+		}
+		o.VideoCodec(opt.VideoCodec).
+			Option("-crf", itoa(opt.CRF), "-preset", opt.Preset, "-force_key_frames", keyframes).
+			AudioCodec(opt.AudioCodec)
+	} else {
+		o.Option("-c", "copy")
+	}
+
+	o.Format("segment")
+	if times != "" {
+		o.Option("-segment_times", times)
+	} else {
+		o.Option("-segment_time", trimFloat(opt.SegmentTime.Seconds()))
+	}
+	if opt.Format != "" {
+		o.Option("-segment_format", opt.Format)
+	}
+	if opt.StartNumber > 0 {
+		o.Option("-segment_start_number", itoa(opt.StartNumber))
+	}
+	o.Option("-reset_timestamps", "1", "-segment_list", list, "-segment_list_type", "csv")
+	return cmd
+}
+
+// segmentEntry segment 列表里的一行
+type segmentEntry struct {
+	name  string
+	start time.Duration
+}
+
+// readSegmentList 读 segment muxer 的 csv 列表：filename,start,end（秒）
+func readSegmentList(path string) ([]segmentEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("read segment list: %w", err)
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("parse segment list: %w", err)
+	}
+	entries := make([]segmentEntry, 0, len(records))
+	for _, rec := range records {
+		if len(rec) == 0 || rec[0] == "" {
+			continue
+		}
+		if len(rec) < 3 {
+			return nil, fmt.Errorf("parse segment list: want filename,start,end, got %q", strings.Join(rec, ","))
+		}
+		secs, err := strconv.ParseFloat(rec[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse segment list: start of %s: %w", rec[0], err)
+		}
+		entries = append(entries, segmentEntry{name: rec[0], start: time.Duration(secs * float64(time.Second))})
+	}
+	return entries, nil
+}
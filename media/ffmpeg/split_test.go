@@ -0,0 +1,49 @@
+package ffmpeg_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/LingByte/LingConvert/media/ffmpeg"
+	fftest "github.com/LingByte/LingConvert/media/testing"
+)
+
+func TestSplitStartFromSegmentList(t *testing.T) {
+	dir := t.TempDir()
+	fake := fftest.NewExecutor()
+	// segment muxer 写的列表：start/end 是片段在输入里的位置，和片段自身时长之和不一定相等
+	fake.OnFunc(func(_ string, args []string) bool {
+		for i := 0; i+1 < len(args); i++ {
+			if args[i] == "-segment_list" {
+				csv := "part_000.mp4,0.000000,4.170000\npart_001.mp4,4.170000,8.341000\n"
+				return os.WriteFile(args[i+1], []byte(csv), 0o644) == nil
+			}
+		}
+		return false
+	}).Exit(0)
+	fake.On("-show_format", "part_").Stdout(`{"format":{"duration":"4.000000"}}`)
+
+	tool := &ffmpeg.FFmpegTool{Executor: fake}
+	segs, err := tool.Split(context.Background(), "in.mp4", ffmpeg.SplitOptions{
+		SegmentTime: 4 * time.Second,
+		Pattern:     filepath.Join(dir, "part_%03d.mp4"),
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ffmpeg.Segment{
+		{Index: 0, Path: filepath.Join(dir, "part_000.mp4"), Start: 0, Duration: 4 * time.Second},
+		{Index: 1, Path: filepath.Join(dir, "part_001.mp4"), Start: 4170 * time.Millisecond, Duration: 4 * time.Second},
+	}
+	if len(segs) != len(want) {
+		t.Fatalf("segments = %+v, want %+v", segs, want)
+	}
+	for i := range want {
+		if segs[i] != want[i] {
+			t.Errorf("segment %d = %+v, want %+v", i, segs[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+)
+
+// DefaultStderrLimit 是 FFmpegTool.StderrLimit 的默认值
+const DefaultStderrLimit = 64 * 1024
+
+// FFmpegLogLine 是 stderr 的一行。
+// 用 LogLevel("level+info") 之类带 level+ 前缀的级别时，ffmpeg 会在每行打印 [info] 等标记，
+// 这里能解析出 Level；否则 Level 为空。
+type FFmpegLogLine struct {
+	Level     string // "quiet"/"panic"/"fatal"/"error"/"warning"/"info"/"verbose"/"debug"/"trace"，未知为空
+	Component string // 例如 "h264 @ 0x55d0c0a3c4c0"，没有为空
+	Message   string
+	Raw       string
+}
+
+// SlogLevel 把 ffmpeg 级别映射到 slog；未知级别按 Info
+func (l FFmpegLogLine) SlogLevel() slog.Level {
+	switch l.Level {
+	case "panic", "fatal", "error":
+		return slog.LevelError
+	case "warning":
+		return slog.LevelWarn
+	case "verbose", "debug", "trace":
+		return slog.LevelDebug
+	default:
+		return slog.LevelInfo
+	}
+}
+
+var ffmpegLevels = map[string]bool{
+	"quiet": true, "panic": true, "fatal": true, "error": true, "warning": true,
+	"info": true, "verbose": true, "debug": true, "trace": true,
+}
+
+func parseLogLine(raw string) FFmpegLogLine {
+	l := FFmpegLogLine{Raw: raw}
+	rest := raw
+	// [component @ 0x...] 前缀
+	if strings.HasPrefix(rest, "[") {
+		if i := strings.Index(rest, "] "); i > 0 && strings.Contains(rest[1:i], " @ ") {
+			l.Component = rest[1:i]
+			rest = rest[i+2:]
+		}
+	}
+	// [level] 前缀（-loglevel level+xxx）
+	if strings.HasPrefix(rest, "[") {
+		if i := strings.Index(rest, "] "); i > 0 && ffmpegLevels[rest[1:i]] {
+			l.Level = rest[1:i]
+			rest = rest[i+2:]
+		} else if strings.HasSuffix(rest, "]") && ffmpegLevels[rest[1:len(rest)-1]] {
+			l.Level = rest[1 : len(rest)-1]
+			rest = ""
+		}
+	}
+	l.Message = rest
+	return l
+}
+
+// tailBuffer 只保留最后 max 字节，写入超过 2*max 时整理一次，摊还 O(1)
+type tailBuffer struct {
+	max int
+	buf []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.buf = append(b.buf, p...)
+	if len(b.buf) > 2*b.max {
+		n := copy(b.buf, b.buf[len(b.buf)-b.max:])
+		b.buf = b.buf[:n]
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	if len(b.buf) > b.max {
+		return string(b.buf[len(b.buf)-b.max:])
+	}
+	return string(b.buf)
+}
+
+// stderrSink 同时写入 tailBuffer，并按行（\n 或 \r）回调 onLine
+type stderrSink struct {
+	tail    tailBuffer
+	onLine  func(FFmpegLogLine)
+	partial []byte
+}
+
+func newStderrSink(limit int, onLine func(FFmpegLogLine)) *stderrSink {
+	if limit <= 0 {
+		limit = DefaultStderrLimit
+	}
+	return &stderrSink{tail: tailBuffer{max: limit}, onLine: onLine}
+}
+
+func (s *stderrSink) Write(p []byte) (int, error) {
+	_, _ = s.tail.Write(p)
+	if s.onLine == nil {
+		return len(p), nil
+	}
+	data := p
+	for len(data) > 0 {
+		i := bytes.IndexAny(data, "\r\n")
+		if i < 0 {
+			s.partial = append(s.partial, data...)
+			// 防止没有换行的超长输出把内存吃掉
+			if len(s.partial) > s.tail.max {
+				s.flush()
+			}
+			break
+		}
+		s.partial = append(s.partial, data[:i]...)
+		s.flush()
+		data = data[i+1:]
+	}
+	return len(p), nil
+}
+
+func (s *stderrSink) flush() {
+	line := strings.TrimRight(string(s.partial), " \t")
+	s.partial = s.partial[:0]
+	if line == "" || s.onLine == nil {
+		return
+	}
+	s.onLine(parseLogLine(line))
+}
+
+func (s *stderrSink) String() string { return s.tail.String() }
+
+// logHook 合并 OnLog 和 Logger 两种回调
+func (t *FFmpegTool) logHook(ctx context.Context) func(FFmpegLogLine) {
+	onLog, logger := t.OnLog, t.Logger
+	if onLog == nil && logger == nil {
+		return nil
+	}
+	return func(l FFmpegLogLine) {
+		if onLog != nil {
+			onLog(l)
+		}
+		if logger != nil {
+			attrs := []slog.Attr{slog.String("tool", "ffmpeg")}
+			if l.Component != "" {
+				attrs = append(attrs, slog.String("component", l.Component))
+			}
+			logger.LogAttrs(ctx, l.SlogLevel(), l.Message, attrs...)
+		}
+	}
+}
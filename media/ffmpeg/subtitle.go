@@ -0,0 +1,269 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// SubtitleFormat 文本字幕格式
+type SubtitleFormat string
+
+const (
+	SubtitleSRT    SubtitleFormat = "srt"
+	SubtitleASS    SubtitleFormat = "ass"
+	SubtitleWebVTT SubtitleFormat = "webvtt"
+)
+
+// ErrBitmapSubtitle 图形字幕（PGS / DVD / DVB）无法转成文本格式，只能烧录或原样复制
+var ErrBitmapSubtitle = errors.New("subtitle: bitmap subtitles cannot be converted to text")
+
+// SubtitleFormatFromPath 按扩展名判断格式：.srt / .ass / .ssa / .vtt
+func SubtitleFormatFromPath(path string) (SubtitleFormat, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".srt":
+		return SubtitleSRT, nil
+	case ".ass", ".ssa":
+		return SubtitleASS, nil
+	case ".vtt":
+		return SubtitleWebVTT, nil
+	}
+	return "", fmt.Errorf("subtitle: unknown subtitle format for %q", path)
+}
+
+// 编码器名和封装格式名正好相同
+func (f SubtitleFormat) codec() string { return string(f) }
+
+func (f SubtitleFormat) valid() bool {
+	return f == SubtitleSRT || f == SubtitleASS || f == SubtitleWebVTT
+}
+
+func isBitmapSubtitle(codec string) bool {
+	switch codec {
+	case "hdmv_pgs_subtitle", "dvd_subtitle", "dvb_subtitle", "xsub":
+		return true
+	}
+	return false
+}
+
+func subtitleOutput(output string, format SubtitleFormat) (SubtitleFormat, error) {
+	if format == "" {
+		return SubtitleFormatFromPath(output)
+	}
+	if !format.valid() {
+		return "", fmt.Errorf("subtitle: unsupported format %q", format)
+	}
+	return format, nil
+}
+
+// ExtractSubtitle 把 input 的第 stream 条字幕流（0:s:N，从 0 开始）导出为文本字幕；
+// format 为空时按 output 扩展名决定
+func (t *FFmpegTool) ExtractSubtitle(ctx context.Context, input string, stream int, output string, format SubtitleFormat) error {
+	format, err := subtitleOutput(output, format)
+	if err != nil {
+		return err
+	}
+	info, err := t.prober().Probe(ctx, input)
+	if err != nil {
+		return fmt.Errorf("subtitle: probe: %w", err)
+	}
+	n := 0
+	found := false
+	for _, s := range info.Streams {
+		if s.CodecType != "subtitle" {
+			continue
+		}
+		if n == stream {
+			if isBitmapSubtitle(s.CodecName) {
+				return fmt.Errorf("%w: stream %d is %s", ErrBitmapSubtitle, stream, s.CodecName)
+			}
+			found = true
+			break
+		}
+		n++
+	}
+	if !found {
+		return fmt.Errorf("subtitle: %s has no subtitle stream #%d", input, stream)
+	}
+
+	cmd := NewFFmpegCommand().HideBanner().LogLevel("error").Input(input)
+	cmd.AddOutput(output).Map("0:s:"+itoa(stream)).Codec("s", format.codec()).Format(string(format))
+	if _, err := t.RunWithProgress(ctx, cmd, nil); err != nil {
+		return fmt.Errorf("subtitle extract: %w", err)
+	}
+	return nil
+}
+
+// ConvertSubtitle 在 SRT / ASS / WebVTT 之间转换；format 为空时按 output 扩展名决定。
+// 转成 SRT / WebVTT 会丢掉 ASS 的样式
+func (t *FFmpegTool) ConvertSubtitle(ctx context.Context, input, output string, format SubtitleFormat) error {
+	format, err := subtitleOutput(output, format)
+	if err != nil {
+		return err
+	}
+	cmd := NewFFmpegCommand().HideBanner().LogLevel("error").Input(input)
+	cmd.AddOutput(output).Map("0:s:0").Codec("s", format.codec()).Format(string(format))
+	if _, err := t.RunWithProgress(ctx, cmd, nil); err != nil {
+		return fmt.Errorf("subtitle convert: %w", err)
+	}
+	return nil
+}
+
+// SubtitleTrack 要封装进去的外挂字幕
+type SubtitleTrack struct {
+	Path     string
+	Language string // ISO 639-2，例如 "chi" / "eng"
+	Title    string
+	Default  bool
+	Forced   bool
+}
+
+// MuxSubtitles 把外挂字幕封装进 output，只带原有的音视频和字幕流（数据流、附件不要），都 stream copy。
+// .mp4 / .m4v / .mov 的新字幕转成 mov_text，原有的文本字幕也转成 mov_text，
+// 原有的图形字幕放不进去，返回 ErrBitmapSubtitle；.mkv 保留原格式
+func (t *FFmpegTool) MuxSubtitles(ctx context.Context, input, output string, tracks []SubtitleTrack) error {
+	if len(tracks) == 0 {
+		return errors.New("subtitle mux: no tracks")
+	}
+	var mp4 bool
+	switch ext := strings.ToLower(filepath.Ext(output)); ext {
+	case ".mp4", ".m4v", ".mov":
+		mp4 = true
+	case ".mkv", ".mka":
+	default:
+		return fmt.Errorf("subtitle mux: unsupported container %q, use .mp4 or .mkv", ext)
+	}
+	info, err := t.prober().Probe(ctx, input)
+	if err != nil {
+		return fmt.Errorf("subtitle: probe: %w", err)
+	}
+
+	cmd := NewFFmpegCommand().HideBanner().LogLevel("error")
+	cmd.AddInput(input)
+	for _, tr := range tracks {
+		cmd.AddInput(tr.Path)
+	}
+	o := cmd.AddOutput(output).Map("0:v?").Map("0:a?").Map("0:s?").Option("-c", "copy")
+
+	// 新字幕流的序号排在原有字幕之后
+	existing := 0
+	for _, s := range info.Streams {
+		if s.CodecType != "subtitle" {
+			continue
+		}
+		if mp4 {
+			if isBitmapSubtitle(s.CodecName) {
+				return fmt.Errorf("%w: %s subtitle stream #%d (%s) cannot go into %s",
+					ErrBitmapSubtitle, input, existing, s.CodecName, filepath.Ext(output))
+			}
+			if s.CodecName != "mov_text" {
+				o.Codec("s:"+itoa(existing), "mov_text")
+			}
+		}
+		existing++
+	}
+	for i, tr := range tracks {
+		o.Map(itoa(i+1) + ":s:0")
+		spec := "s:" + itoa(existing+i)
+		if mp4 {
+			o.Codec(spec, "mov_text")
+		}
+		if tr.Language != "" {
+			o.Option("-metadata:s:"+spec, "language="+tr.Language)
+		}
+		if tr.Title != "" {
+			o.Option("-metadata:s:"+spec, "title="+tr.Title)
+		}
+		o.Option("-disposition:"+spec, subtitleDisposition(tr))
+	}
+	if _, err := t.RunWithProgress(ctx, cmd, nil); err != nil {
+		return fmt.Errorf("subtitle mux: %w", err)
+	}
+	return nil
+}
+
+func subtitleDisposition(tr SubtitleTrack) string {
+	var flags []string
+	if tr.Default {
+		flags = append(flags, "default")
+	}
+	if tr.Forced {
+		flags = append(flags, "forced")
+	}
+	if len(flags) == 0 {
+		return "0"
+	}
+	return strings.Join(flags, "+")
+}
+
+// BurnOptions 硬字幕参数
+type BurnOptions struct {
+	StreamIndex int    // 字幕来自视频本身时，用第几条字幕流（si）
+	ForceStyle  string // ASS 样式覆盖，例如 "FontName=Noto Sans CJK SC,FontSize=24"
+	Charenc     string // 非 UTF-8 的 SRT 需要指定，例如 "GBK"
+	FontsDir    string // 额外的字体目录
+
+	VideoCodec string // 默认 libx264
+	CRF        int    // 默认 23
+	Preset     string // 默认 medium
+	AudioCodec string // 默认 copy
+}
+
+// SubtitlesFilter 构造 subtitles 滤镜；路径和样式里的 : ' \ [ ] , ; 等按 filtergraph 的两层转义规则处理，
+// Windows 路径、带引号或逗号的文件名都可以直接传
+func SubtitlesFilter(path string, opt BurnOptions) *Filter {
+	f := NewFilter("subtitles").Opt("filename", path)
+	if opt.StreamIndex > 0 {
+		f.Opt("si", itoa(opt.StreamIndex))
+	}
+	if opt.Charenc != "" {
+		f.Opt("charenc", opt.Charenc)
+	}
+	if opt.FontsDir != "" {
+		f.Opt("fontsdir", opt.FontsDir)
+	}
+	if opt.ForceStyle != "" {
+		f.Opt("force_style", opt.ForceStyle)
+	}
+	return f
+}
+
+// BurnSubtitles 把字幕烧进画面（需要重新编码视频）；subtitles 为空时使用 input 自带的第 opt.StreamIndex 条字幕
+func (t *FFmpegTool) BurnSubtitles(
+	ctx context.Context,
+	input, subtitles, output string,
+	opt BurnOptions,
+	onProgress func(p FFmpegProgress) error,
+) (FFmpegProgress, error) {
+	if subtitles == "" {
+		subtitles = input
+	}
+	if opt.VideoCodec == "" {
+		opt.VideoCodec = "libx264"
+	}
+	if opt.CRF <= 0 {
+		opt.CRF = 23
+	}
+	if opt.Preset == "" {
+		opt.Preset = "medium"
+	}
+	if opt.AudioCodec == "" {
+		opt.AudioCodec = "copy"
+	}
+	cmd := NewFFmpegCommand().HideBanner().LogLevel("error").RequireLibrary("libass", "", "subtitles filter")
+	cmd.AddInput(input)
+	o := cmd.AddOutput(output).Map("0:v:0").Map("0:a?").
+		VideoFilter(SubtitlesFilter(subtitles, opt)).
+		VideoCodec(opt.VideoCodec)
+	if opt.VideoCodec == "libx264" || opt.VideoCodec == "libx265" {
+		o.Option("-crf", itoa(opt.CRF), "-preset", opt.Preset)
+	}
+	o.AudioCodec(opt.AudioCodec)
+	p, err := t.RunWithProgress(ctx, cmd, onProgress)
+	if err != nil {
+		return p, fmt.Errorf("subtitle burn: %w", err)
+	}
+	return p, nil
+}
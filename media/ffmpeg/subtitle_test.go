@@ -0,0 +1,179 @@
+package ffmpeg_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/LingByte/LingConvert/media/ffmpeg"
+	fftest "github.com/LingByte/LingConvert/media/testing"
+)
+
+// unescapeFilter 按 ffmpeg 的顺序还原：先去掉 filtergraph 层的转义，再按未转义的 : 切分选项并去掉选项层的转义
+func unescapeFilter(t *testing.T, s string) map[string]string {
+	t.Helper()
+	unescape := func(s string, sep byte) []string {
+		var parts []string
+		var cur strings.Builder
+		for i := 0; i < len(s); i++ {
+			switch {
+			case s[i] == '\\' && i+1 < len(s):
+				i++
+				if sep == 0 {
+					cur.WriteByte(s[i])
+				} else {
+					// 选项层还要再还原一次，这里先保留转义
+					cur.WriteByte('\\')
+					cur.WriteByte(s[i])
+				}
+			case sep != 0 && s[i] == sep:
+				parts = append(parts, cur.String())
+				cur.Reset()
+			default:
+				cur.WriteByte(s[i])
+			}
+		}
+		return append(parts, cur.String())
+	}
+	graph := unescape(s, 0)[0]
+	name, args, ok := strings.Cut(graph, "=")
+	if !ok || name != "subtitles" {
+		t.Fatalf("filter = %q", graph)
+	}
+	opts := map[string]string{}
+	for _, kv := range unescape(args, ':') {
+		k, v, _ := strings.Cut(kv, "=")
+		opts[k] = unescape(v, 0)[0]
+	}
+	return opts
+}
+
+func TestSubtitlesFilter(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		opt  ffmpeg.BurnOptions
+		want string
+	}{
+		{"windows drive", `C:\subs\movie.srt`, ffmpeg.BurnOptions{}, `subtitles=filename=C\\:\\\\subs\\\\movie.srt`},
+		{"quote", `it's.srt`, ffmpeg.BurnOptions{}, `subtitles=filename=it\\\'s.srt`},
+		{"comma brackets semicolon", `a,b[1];c.srt`, ffmpeg.BurnOptions{}, `subtitles=filename=a\,b\[1\]\;c.srt`},
+		{"force_style", "x.srt", ffmpeg.BurnOptions{ForceStyle: "FontName=Noto Sans,FontSize=24"},
+			`subtitles=filename=x.srt:force_style=FontName=Noto Sans\,FontSize=24`},
+		{"all options", `D:\a b\'x'.ass`, ffmpeg.BurnOptions{StreamIndex: 1, Charenc: "GBK", FontsDir: `C:\fonts`},
+			`subtitles=filename=D\\:\\\\a b\\\\\\\'x\\\'.ass:si=1:charenc=GBK:fontsdir=C\\:\\\\fonts`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := ffmpeg.SubtitlesFilter(tt.path, tt.opt)
+			got := f.String()
+			if got != tt.want {
+				t.Errorf("SubtitlesFilter = %s, want %s", got, tt.want)
+			}
+			opts := unescapeFilter(t, got)
+			if opts["filename"] != tt.path {
+				t.Errorf("filename unescapes to %q, want %q", opts["filename"], tt.path)
+			}
+			if tt.opt.ForceStyle != "" && opts["force_style"] != tt.opt.ForceStyle {
+				t.Errorf("force_style unescapes to %q, want %q", opts["force_style"], tt.opt.ForceStyle)
+			}
+			if tt.opt.FontsDir != "" && opts["fontsdir"] != tt.opt.FontsDir {
+				t.Errorf("fontsdir unescapes to %q, want %q", opts["fontsdir"], tt.opt.FontsDir)
+			}
+		})
+	}
+}
+
+func subtitleFake(streams string) *fftest.Executor {
+	fake := fftest.NewExecutor()
+	fake.On("-show_streams").Stdout(`{"streams":[` + streams + `],"format":{}}`)
+	fake.On()
+	return fake
+}
+
+const (
+	videoStream   = `{"codec_type":"video","codec_name":"h264"}`
+	audioStream   = `{"codec_type":"audio","codec_name":"aac"}`
+	srtStream     = `{"codec_type":"subtitle","codec_name":"subrip"}`
+	movTextStream = `{"codec_type":"subtitle","codec_name":"mov_text"}`
+	pgsStream     = `{"codec_type":"subtitle","codec_name":"hdmv_pgs_subtitle"}`
+)
+
+func TestMuxSubtitlesArgs(t *testing.T) {
+	fake := subtitleFake(strings.Join([]string{videoStream, audioStream, srtStream, movTextStream}, ","))
+	tool := &ffmpeg.FFmpegTool{Executor: fake}
+	err := tool.MuxSubtitles(context.Background(), "in.mkv", "out.mp4", []ffmpeg.SubtitleTrack{
+		{Path: "en.srt", Language: "eng", Title: "English", Default: true},
+		{Path: "zh.ass", Language: "chi", Forced: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	runs := ffmpegRuns(fake)
+	if len(runs) != 1 {
+		t.Fatalf("ffmpeg runs = %q", runs)
+	}
+	run := runs[0]
+	for _, want := range []string{
+		" -map 0:v? -map 0:a? -map 0:s? -c copy -c:s:0 mov_text ",
+		// 原有两条字幕之后，新字幕是 s:2 / s:3
+		" -map 1:s:0 -c:s:2 mov_text -metadata:s:s:2 language=eng -metadata:s:s:2 title=English -disposition:s:2 default ",
+		" -map 2:s:0 -c:s:3 mov_text -metadata:s:s:3 language=chi -disposition:s:3 forced ",
+	} {
+		if !strings.Contains(run, want) {
+			t.Errorf("args = %q, missing %q", run, want)
+		}
+	}
+	for _, bad := range []string{" -map 0 ", " -c:s mov_text ", " -c:s:1 "} {
+		if strings.Contains(run, bad) {
+			t.Errorf("args = %q, must not contain %q", run, bad)
+		}
+	}
+}
+
+func TestMuxSubtitlesBitmap(t *testing.T) {
+	streams := strings.Join([]string{videoStream, pgsStream}, ",")
+	track := []ffmpeg.SubtitleTrack{{Path: "en.srt"}}
+
+	fake := subtitleFake(streams)
+	err := (&ffmpeg.FFmpegTool{Executor: fake}).MuxSubtitles(context.Background(), "in.mkv", "out.mp4", track)
+	if !errors.Is(err, ffmpeg.ErrBitmapSubtitle) {
+		t.Fatalf("err = %v, want ErrBitmapSubtitle", err)
+	}
+	if runs := ffmpegRuns(fake); len(runs) != 0 {
+		t.Errorf("ffmpeg ran: %q", runs)
+	}
+
+	// mkv 原样复制图形字幕
+	fake = subtitleFake(streams)
+	if err := (&ffmpeg.FFmpegTool{Executor: fake}).MuxSubtitles(context.Background(), "in.mkv", "out.mkv", track); err != nil {
+		t.Fatal(err)
+	}
+	runs := ffmpegRuns(fake)
+	if len(runs) != 1 || strings.Contains(runs[0], "mov_text") || !strings.Contains(runs[0], " -map 1:s:0 -disposition:s:1 0 ") {
+		t.Errorf("ffmpeg runs = %q", runs)
+	}
+}
+
+func TestExtractSubtitle(t *testing.T) {
+	streams := strings.Join([]string{videoStream, srtStream, pgsStream}, ",")
+
+	fake := subtitleFake(streams)
+	err := (&ffmpeg.FFmpegTool{Executor: fake}).ExtractSubtitle(context.Background(), "in.mkv", 1, "out.srt", "")
+	if !errors.Is(err, ffmpeg.ErrBitmapSubtitle) {
+		t.Fatalf("err = %v, want ErrBitmapSubtitle", err)
+	}
+	if runs := ffmpegRuns(fake); len(runs) != 0 {
+		t.Errorf("ffmpeg ran: %q", runs)
+	}
+
+	fake = subtitleFake(streams)
+	if err := (&ffmpeg.FFmpegTool{Executor: fake}).ExtractSubtitle(context.Background(), "in.mkv", 0, "out.vtt", ""); err != nil {
+		t.Fatal(err)
+	}
+	runs := ffmpegRuns(fake)
+	if len(runs) != 1 || !strings.Contains(runs[0], " -map 0:s:0 -c:s webvtt -f webvtt out.vtt ") {
+		t.Errorf("ffmpeg runs = %q", runs)
+	}
+}
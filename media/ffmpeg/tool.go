@@ -1,28 +1,57 @@
 package ffmpeg
 
 import (
-	"bytes"
 	"context"
-	"errors"
-	"fmt"
-	"os/exec"
+	"log/slog"
 	"strconv"
-	"strings"
 	"sync"
 	"time"
+
+	"github.com/LingByte/LingConvert/media/ffprobe"
+	"github.com/LingByte/LingConvert/media/locate"
+	"github.com/LingByte/LingConvert/media/proc"
 )
 
 type FFmpegTool struct {
 	FFmpegPath string        // default "ffmpeg"
 	Timeout    time.Duration // 0 = no timeout (recommended for long transcodes)
 
-	mu           sync.Mutex
-	checked      bool
-	resolvedPath string
-	version      string
-	checkErr     error
+	// stderr 只保留末尾 StderrLimit 字节用于错误信息，0 = DefaultStderrLimit
+	StderrLimit int
+	// 每行 stderr 实时回调（在读取 goroutine 中调用，不要阻塞）
+	OnLog func(line FFmpegLogLine)
+	// 非 nil 时每行 stderr 按解析出的级别写入 Logger
+	Logger *slog.Logger
+	// 非 nil 时，带进度回调的执行会先探测输入时长，用于计算 Percent / ETA
+	Prober *ffprobe.Tool
+
+	// 取消/超时时如何停止 ffmpeg，默认 StopKill（直接杀进程，mp4 可能缺 moov 无法播放）
+	StopMode StopMode
+	// 优雅停止后等待 ffmpeg 收尾的时间，超时再杀；0 = DefaultStopGrace
+	StopGrace time.Duration
+
+	// 进程组 / nice / ionice / 线程数 / rlimit 限制，零值不限制
+	Limits proc.Limits
+	// 启动进程的后端，nil = proc.OS；测试时可换成 media/testing 的假实现
+	Executor proc.Executor
+
+	mu  sync.Mutex
+	loc *locate.Locator
 }
 
+// Capabilities 见 locate.Capabilities
+type Capabilities = locate.Capabilities
+
+type StopMode int
+
+const (
+	StopKill      StopMode = iota // 直接 SIGKILL
+	StopQuit                      // 往 stdin 写 "q"，和交互式按 q 一样；用了 RunOptions.Stdin 时退化为 StopInterrupt
+	StopInterrupt                 // 发送 SIGINT（Windows 不支持，会直接 kill）
+)
+
+const DefaultStopGrace = 10 * time.Second
+
 func NewDefaultFFmpeg() *FFmpegTool {
 	return &FFmpegTool{
 		FFmpegPath: "ffmpeg",
@@ -30,91 +59,54 @@ func NewDefaultFFmpeg() *FFmpegTool {
 	}
 }
 
-func (t *FFmpegTool) ensureReady(ctx context.Context) error {
+// locator 懒创建，FFmpegPath / Executor 在第一次使用后再修改不会生效
+func (t *FFmpegTool) locator() *locate.Locator {
 	t.mu.Lock()
-	if t.checked {
-		err := t.checkErr
-		t.mu.Unlock()
-		return err
-	}
-	t.mu.Unlock()
-
-	path := t.FFmpegPath
-	if path == "" {
-		path = "ffmpeg"
+	defer t.mu.Unlock()
+	if t.loc == nil {
+		t.loc = &locate.Locator{Name: "ffmpeg", Path: t.FFmpegPath, Env: locate.EnvFFmpeg, Executor: t.Executor}
 	}
+	return t.loc
+}
 
-	resolved, err := exec.LookPath(path)
-	if err != nil {
-		t.mu.Lock()
-		t.checked = true
-		t.checkErr = fmt.Errorf("ffmpeg not found (FFmpegPath=%q): %w", path, err)
-		t.mu.Unlock()
-		return t.checkErr
-	}
+// binary 返回 ffmpeg 的绝对路径；失败结果按退避间隔重试，不会永久缓存
+func (t *FFmpegTool) binary(ctx context.Context) (string, error) {
+	return t.locator().Resolve(ctx)
+}
 
-	// quick -version
-	cctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-	defer cancel()
-
-	cmd := exec.CommandContext(cctx, resolved, "-version")
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	runErr := cmd.Run()
-	if runErr != nil {
-		var finalErr error
-		if errors.Is(cctx.Err(), context.DeadlineExceeded) {
-			finalErr = fmt.Errorf("ffmpeg check timed out (path=%q)", resolved)
-		} else {
-			finalErr = fmt.Errorf("ffmpeg exists but cannot run (path=%q): %w; stderr=%s",
-				resolved, runErr, strings.TrimSpace(stderr.String()))
-		}
-		t.mu.Lock()
-		t.checked = true
-		t.resolvedPath = resolved
-		t.checkErr = finalErr
-		t.mu.Unlock()
-		return finalErr
+func (t *FFmpegTool) executor() proc.Executor {
+	if t.Executor != nil {
+		return t.Executor
 	}
+	return proc.OS
+}
 
-	ver := parseFFmpegVersion(stdout.String())
-	if ver == "" {
-		ver = "unknown"
-	}
+func (t *FFmpegTool) Version(ctx context.Context) (string, error) {
+	return t.locator().Version(ctx)
+}
 
-	t.mu.Lock()
-	t.checked = true
-	t.resolvedPath = resolved
-	t.version = ver
-	t.checkErr = nil
-	t.mu.Unlock()
-	return nil
+// Capabilities 编译进来的编解码器、滤镜、封装格式和协议（首次调用会执行 ffmpeg 5 次，之后缓存）
+func (t *FFmpegTool) Capabilities(ctx context.Context) (*Capabilities, error) {
+	return t.locator().Capabilities(ctx)
 }
 
-func (t *FFmpegTool) Version(ctx context.Context) (string, error) {
-	if err := t.ensureReady(ctx); err != nil {
-		return "", err
+// NewProber 返回一个优先使用 ffmpeg 同目录下 ffprobe 的 ffprobe.Tool，执行方式和资源限制与 t 相同
+func (t *FFmpegTool) NewProber() *ffprobe.Tool {
+	p := ffprobe.NewDefaultTool()
+	if t.FFmpegPath != "" && t.FFmpegPath != "ffmpeg" {
+		p.NextTo = t.FFmpegPath
 	}
-	t.mu.Lock()
-	defer t.mu.Unlock()
-	return t.version, nil
+	p.Executor = t.Executor
+	p.Limits = t.Limits
+	return p
 }
 
-func parseFFmpegVersion(s string) string {
-	lines := strings.Split(s, "\n")
-	if len(lines) == 0 {
-		return ""
-	}
-	first := strings.TrimSpace(lines[0])
-	parts := strings.Fields(first)
-	for i := 0; i < len(parts)-1; i++ {
-		if strings.ToLower(parts[i]) == "version" {
-			return parts[i+1]
-		}
+// prober 返回 t.Prober，没设置时新建一个（拼接/切分等需要探测输入的功能用）
+func (t *FFmpegTool) prober() *ffprobe.Tool {
+	if t.Prober != nil {
+		return t.Prober
 	}
-	return ""
+	return t.NewProber()
 }
 
 func minDuration(a, b time.Duration) time.Duration {
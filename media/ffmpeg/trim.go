@@ -0,0 +1,459 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LingByte/LingConvert/media/ffprobe"
+)
+
+type TrimMode int
+
+const (
+	// TrimFast 输入侧 -ss + stream copy：最快，但起点会落到 start 之前最近的关键帧
+	TrimFast TrimMode = iota
+	// TrimExact 整段重新编码，帧级精确
+	TrimExact
+	// TrimSmart 只重新编码切点两侧不完整的 GOP，中间 stream copy，再用 concat demuxer 拼起来；
+	// 精确且大部分画面不损失画质，音频整段重新编码。只支持 H.264 / HEVC，
+	// 条件不满足时（纯音频、区间内没有完整 GOP、没有对应的编码器）在编码前退化为 TrimExact
+	TrimSmart
+)
+
+func (m TrimMode) String() string {
+	switch m {
+	case TrimExact:
+		return "exact"
+	case TrimSmart:
+		return "smart"
+	}
+	return "fast"
+}
+
+// TrimOptions 重新编码部分的参数；为空时 smart 模式按源文件的编码选择，exact 模式用 libx264 / aac
+type TrimOptions struct {
+	Mode TrimMode
+
+	VideoCodec string
+	AudioCodec string
+	CRF        int    // 默认 18（smart 模式的边缘片段要和中间的原画质接近）/ exact 23
+	Preset     string // 默认 medium
+
+	FastStart bool // mp4 输出时加 +faststart
+}
+
+// TrimResult 实际使用的方式；Reason 说明 smart 为什么退化，Reencoded 是重新编码的视频时长
+// （截到结尾时按探测到的时长计算，探测失败为 0）
+type TrimResult struct {
+	Mode      TrimMode
+	Reason    string
+	Reencoded time.Duration
+	Progress  FFmpegProgress
+}
+
+// keyframeEpsilon 切点和关键帧相差不超过这么多就认为重合
+const keyframeEpsilon = time.Millisecond
+
+// Trim 截取 input 的 [start, end) 写到 output；end <= 0 表示到结尾
+func (t *FFmpegTool) Trim(
+	ctx context.Context,
+	input, output string,
+	start, end time.Duration,
+	opt TrimOptions,
+	onProgress func(p FFmpegProgress) error,
+) (*TrimResult, error) {
+	if start < 0 || (end > 0 && end <= start) {
+		return nil, fmt.Errorf("trim: invalid range %s - %s", start, end)
+	}
+	res := &TrimResult{Mode: opt.Mode}
+	if opt.Mode == TrimSmart {
+		done, err := t.trimSmart(ctx, input, output, start, end, opt, onProgress, res)
+		if done || err != nil {
+			return res, err
+		}
+		res.Mode = TrimExact
+	}
+
+	cmd := NewFFmpegCommand().HideBanner().LogLevel("error")
+	in := cmd.AddInput(input).Seek(start.Seconds())
+	if end > 0 {
+		in.Duration((end - start).Seconds())
+	}
+	o := cmd.AddOutput(output).Map("0:v?").Map("0:a?")
+	if res.Mode == TrimFast {
+		o.Option("-c", "copy", "-avoid_negative_ts", "make_zero")
+	} else {
+		if opt.CRF <= 0 {
+			opt.CRF = 23
+		}
+		trimEncodeOptions(o, opt, nil, "libx264", "aac")
+		if end > 0 {
+			res.Reencoded = end - start
+		} else if info, err := t.prober().Probe(ctx, input); err == nil {
+			// 只用于统计，探测失败不影响截取
+			if total := probedDuration(info); total > start {
+				res.Reencoded = total - start
+			}
+		}
+	}
+	if opt.FastStart {
+		o.Option("-movflags", "+faststart")
+	}
+	p, err := t.RunWithProgress(ctx, cmd, onProgress)
+	res.Progress = p
+	if err != nil {
+		return res, fmt.Errorf("trim (%s): %w", res.Mode, err)
+	}
+	return res, nil
+}
+
+// smartCodec smart 模式支持的视频编码：边缘用的编码器，stream copy 部分转成 Annex B
+// （参数集随关键帧写进码流）的 bsf，以及 mp4 里允许码流内参数集的 tag
+type smartCodec struct {
+	encoder, bsf, tag string
+}
+
+var smartCodecs = map[string]smartCodec{
+	"h264": {"libx264", "h264_mp4toannexb", "avc3"},
+	"hevc": {"libx265", "hevc_mp4toannexb", "hev1"},
+}
+
+// trimSmart 返回 done=false 表示需要退化成整段重新编码，原因写在 res.Reason。
+// 能否拼接在编码之前判断完：视频片段写成 MPEG-TS，每段自带参数集，边缘的 profile / level / 像素格式跟源一致；
+// 音频整段重新编码一次，最后和拼好的视频一起封装
+func (t *FFmpegTool) trimSmart(
+	ctx context.Context,
+	input, output string,
+	start, end time.Duration,
+	opt TrimOptions,
+	onProgress func(p FFmpegProgress) error,
+	res *TrimResult,
+) (bool, error) {
+	prober := t.prober()
+	info, err := prober.Probe(ctx, input)
+	if err != nil {
+		return false, fmt.Errorf("trim: probe: %w", err)
+	}
+	v, a := info.FirstVideo(), info.FirstAudio()
+	if v == nil {
+		res.Reason = "no video stream"
+		return false, nil
+	}
+	sc, ok := smartCodecs[v.CodecName]
+	if !ok {
+		res.Reason = fmt.Sprintf("video codec %q is not supported", v.CodecName)
+		return false, nil
+	}
+	if opt.VideoCodec != "" && opt.VideoCodec != sc.encoder {
+		res.Reason = fmt.Sprintf("video encoder %s does not match the source codec %s", opt.VideoCodec, v.CodecName)
+		return false, nil
+	}
+	opt.VideoCodec = sc.encoder
+	if a != nil && opt.AudioCodec == "" {
+		if opt.AudioCodec = encoderFor(a.CodecName); opt.AudioCodec == "" {
+			res.Reason = fmt.Sprintf("no encoder for the source audio codec %q", a.CodecName)
+			return false, nil
+		}
+	}
+	caps, err := t.Capabilities(ctx)
+	if err != nil {
+		return false, fmt.Errorf("trim: %w", err)
+	}
+	encoders := []string{opt.VideoCodec}
+	if a != nil {
+		// 没有音频时 AudioCodec 用不上，不因为它退化
+		encoders = append(encoders, opt.AudioCodec)
+	}
+	for _, enc := range encoders {
+		if !caps.HasEncoder(enc) {
+			res.Reason = fmt.Sprintf("encoder %s is not available", enc)
+			return false, nil
+		}
+	}
+
+	if total := probedDuration(info); end > 0 && total > 0 && end >= total {
+		end = 0
+	}
+	pk, err := prober.ProbePackets(ctx, input, "v:0")
+	if err != nil {
+		return false, fmt.Errorf("trim: probe packets: %w", err)
+	}
+	// pts_time 是绝对时间，-ss 相对文件开头
+	keys := keyframeTimes(pk.Packets, formatStart(info))
+
+	// k1：start 之后第一个关键帧；k2：end 之前最后一个关键帧
+	i := sort.Search(len(keys), func(i int) bool { return keys[i] >= start-keyframeEpsilon })
+	if i == len(keys) {
+		res.Reason = "no keyframe after start"
+		return false, nil
+	}
+	k1, k2 := keys[i], time.Duration(0)
+	if end > 0 {
+		j := sort.Search(len(keys), func(j int) bool { return keys[j] > end+keyframeEpsilon }) - 1
+		if j < 0 || keys[j] <= k1 {
+			res.Reason = "no complete GOP inside the range"
+			return false, nil
+		}
+		k2 = keys[j]
+		if end-k2 <= keyframeEpsilon {
+			k2 = end
+		}
+	}
+	if k1-start <= keyframeEpsilon {
+		k1 = start
+	}
+	if opt.CRF <= 0 {
+		opt.CRF = 18
+	}
+
+	// 两端都落在关键帧上：直接 stream copy
+	if k1 == start && (end == 0 || k2 == end) {
+		cmd := NewFFmpegCommand().HideBanner().LogLevel("error")
+		in := cmd.AddInput(input).Seek(start.Seconds())
+		if end > 0 {
+			in.Duration((end - start).Seconds())
+		}
+		o := cmd.AddOutput(output).Map("0:v:0").Map("0:a:0?").Option("-c", "copy", "-avoid_negative_ts", "make_zero")
+		if opt.FastStart {
+			o.Option("-movflags", "+faststart")
+		}
+		p, err := t.RunWithProgress(ctx, cmd, onProgress)
+		res.Progress = p
+		if err != nil {
+			return true, fmt.Errorf("trim (smart): %w", err)
+		}
+		return true, nil
+	}
+
+	type part struct {
+		from, to time.Duration // to=0 到结尾
+		encode   bool
+	}
+	var parts []part
+	if k1 > start {
+		parts = append(parts, part{start, k1, true})
+	}
+	parts = append(parts, part{k1, k2, false})
+	if end > 0 && end > k2 {
+		parts = append(parts, part{k2, end, true})
+	}
+
+	dir, err := os.MkdirTemp("", "fftrim-*")
+	if err != nil {
+		return false, fmt.Errorf("trim: create temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	passes := len(parts) + 1
+	paths := make([]string, len(parts))
+	for n, pt := range parts {
+		paths[n] = filepath.Join(dir, "part"+itoa(n)+".ts")
+		cmd := NewFFmpegCommand().HideBanner().LogLevel("error")
+		in := cmd.AddInput(input).Seek(pt.from.Seconds())
+		if pt.to > 0 {
+			in.Duration((pt.to - pt.from).Seconds())
+		}
+		o := cmd.AddOutput(paths[n]).Map("0:v:0")
+		if pt.encode {
+			smartEncodeOptions(o, opt, v)
+			res.Reencoded += pt.to - pt.from
+		} else {
+			o.Option("-c:v", "copy", "-bsf:v", sc.bsf)
+		}
+		o.Format("mpegts")
+		p, err := t.RunWithProgress(ctx, cmd, passProgress(n+1, passes, onProgress))
+		res.Progress = p
+		if err != nil {
+			return true, fmt.Errorf("trim (smart) part %d: %w", n, err)
+		}
+	}
+
+	list := filepath.Join(dir, "list.ffconcat")
+	data, err := ConcatList(paths)
+	if err != nil {
+		return true, fmt.Errorf("trim (smart): %w", err)
+	}
+	if err := os.WriteFile(list, data, 0o600); err != nil {
+		return true, fmt.Errorf("trim (smart): write list file: %w", err)
+	}
+	cmd := NewFFmpegCommand().HideBanner().LogLevel("error")
+	cmd.AddInput(list).Format("concat").Option("-safe", "0")
+	o := cmd.AddOutput(output).Map("0:v:0").Option("-c:v", "copy")
+	if a != nil {
+		// 音频整段编码一次，接缝处不会有编码器 priming 造成的空隙
+		ain := cmd.AddInput(input).Seek(start.Seconds())
+		if end > 0 {
+			ain.Duration((end - start).Seconds())
+		}
+		o.Map("1:a:0").AudioCodec(opt.AudioCodec)
+		if a.SampleRate != "" {
+			o.Option("-ar", a.SampleRate)
+		}
+		if a.Channels > 0 {
+			o.Option("-ac", itoa(a.Channels))
+		}
+		if a.BitRate != "" {
+			o.Option("-b:a", a.BitRate)
+		}
+	}
+	if isMP4Like(filepath.Ext(output)) {
+		// avcC / hvcC 只能放一组参数集，avc3 / hev1 允许码流里的参数集在片段之间切换
+		o.Option("-tag:v", sc.tag)
+		if ts := timescale(v.TimeBase); ts != "" {
+			o.Option("-video_track_timescale", ts)
+		}
+	}
+	if opt.FastStart {
+		o.Option("-movflags", "+faststart")
+	}
+	p, err := t.RunWithProgress(ctx, cmd, passProgress(passes, passes, onProgress))
+	res.Progress = p
+	if err != nil {
+		return true, fmt.Errorf("trim (smart): %w", err)
+	}
+	return true, nil
+}
+
+// smartEncodeOptions 边缘片段的编码参数：profile、level、像素格式和色彩参数都跟源视频一致，
+// 和 stream copy 的部分拼起来解码器不需要重新初始化
+func smartEncodeOptions(o *OutputSpec, opt TrimOptions, src *ffprobe.Stream) {
+	opt.AudioCodec = ""
+	trimEncodeOptions(o, opt, src, "", "")
+	if p := encoderProfile(src); p != "" {
+		o.Option("-profile:v", p)
+	}
+	switch {
+	case src.CodecName == "h264" && src.Level >= 10:
+		o.Option("-level", strconv.FormatFloat(float64(src.Level)/10, 'f', 1, 64))
+	case src.CodecName == "hevc" && src.Level > 0:
+		o.Option("-x265-params", "level-idc="+strconv.FormatFloat(float64(src.Level)/30, 'f', 1, 64))
+	}
+	for _, c := range []struct{ name, value string }{
+		{"-color_range", src.ColorRange},
+		{"-colorspace", src.ColorSpace},
+		{"-color_trc", src.ColorTransfer},
+		{"-color_primaries", src.ColorPrimaries},
+	} {
+		if c.value != "" && c.value != "unknown" {
+			o.Option(c.name, c.value)
+		}
+	}
+}
+
+// encoderProfile ffprobe 的 profile 名对应的 libx264 / libx265 -profile:v，不认识返回 ""
+func encoderProfile(s *ffprobe.Stream) string {
+	switch s.CodecName + "/" + s.Profile {
+	case "h264/Baseline", "h264/Constrained Baseline":
+		return "baseline"
+	case "h264/Main", "hevc/Main":
+		return "main"
+	case "h264/High":
+		return "high"
+	case "h264/High 10":
+		return "high10"
+	case "h264/High 4:2:2":
+		return "high422"
+	case "h264/High 4:4:4 Predictive":
+		return "high444"
+	case "hevc/Main 10":
+		return "main10"
+	}
+	return ""
+}
+
+// trimEncodeOptions 重新编码的参数；src 非 nil 时保持源视频的像素格式，尽量和 stream copy 的部分一致
+func trimEncodeOptions(o *OutputSpec, opt TrimOptions, src *ffprobe.Stream, defVideo, defAudio string) {
+	if opt.VideoCodec == "" {
+		opt.VideoCodec = defVideo
+	}
+	if opt.AudioCodec == "" {
+		opt.AudioCodec = defAudio
+	}
+	if opt.Preset == "" {
+		opt.Preset = "medium"
+	}
+	o.VideoCodec(opt.VideoCodec)
+	if opt.VideoCodec == "libx264" || opt.VideoCodec == "libx265" {
+		o.Option("-crf", itoa(opt.CRF), "-preset", opt.Preset)
+	}
+	if src != nil && src.PixFmt != "" {
+		o.Option("-pix_fmt", src.PixFmt)
+	}
+	if opt.AudioCodec != "" {
+		o.AudioCodec(opt.AudioCodec)
+	}
+}
+
+// encoderFor 源编码对应的常用编码器，不认识返回 ""
+func encoderFor(codec string) string {
+	switch codec {
+	case "h264":
+		return "libx264"
+	case "hevc":
+		return "libx265"
+	case "vp9":
+		return "libvpx-vp9"
+	case "mpeg4":
+		return "mpeg4"
+	case "aac":
+		return "aac"
+	case "mp3":
+		return "libmp3lame"
+	case "opus":
+		return "libopus"
+	case "ac3":
+		return "ac3"
+	case "flac":
+		return "flac"
+	}
+	return ""
+}
+
+// formatStart 容器的 start_time，没有时为 0
+func formatStart(info *ffprobe.FFProbeJSON) time.Duration {
+	secs, err := strconv.ParseFloat(info.Format.StartTime, 64)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(secs * float64(time.Second))
+}
+
+// keyframeTimes 带 K 标记的包相对 origin 的时间，升序
+func keyframeTimes(packets []ffprobe.Packet, origin time.Duration) []time.Duration {
+	var out []time.Duration
+	for _, p := range packets {
+		if !strings.Contains(p.Flags, "K") {
+			continue
+		}
+		secs, err := strconv.ParseFloat(p.PtsTime, 64)
+		if err != nil {
+			continue
+		}
+		out = append(out, time.Duration(secs*float64(time.Second))-origin)
+	}
+	sort.Slice(out, func(i, k int) bool { return out[i] < out[k] })
+	return out
+}
+
+// timescale "1/15360" => "15360"
+func timescale(tb string) string {
+	num, den, ok := strings.Cut(tb, "/")
+	if !ok || num != "1" || den == "" || den == "0" {
+		return ""
+	}
+	return den
+}
+
+func isMP4Like(ext string) bool {
+	switch strings.ToLower(ext) {
+	case ".mp4", ".m4v", ".mov":
+		return true
+	}
+	return false
+}
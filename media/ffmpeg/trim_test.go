@@ -0,0 +1,199 @@
+package ffmpeg_test
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LingByte/LingConvert/media/ffmpeg"
+	"github.com/LingByte/LingConvert/media/ffprobe"
+	fftest "github.com/LingByte/LingConvert/media/testing"
+)
+
+// 源文件 start_time=1.4，每 2 秒一个关键帧：相对开头是 0、2、4、6、8 秒
+const (
+	trimProbeJSON = `{"streams":[
+		{"index":0,"codec_name":"h264","codec_type":"video","profile":"High","level":40,"pix_fmt":"yuv420p",
+		 "color_range":"tv","color_primaries":"bt709","time_base":"1/12800"},
+		{"index":1,"codec_name":"aac","codec_type":"audio","sample_rate":"48000","channels":2}],
+		"format":{"start_time":"1.400000","duration":"10.000000"}}`
+	trimPacketsJSON = `{"packets":[
+		{"codec_type":"video","pts_time":"1.400000","flags":"K__"},
+		{"codec_type":"video","pts_time":"2.400000","flags":"___"},
+		{"codec_type":"video","pts_time":"3.400000","flags":"K__"},
+		{"codec_type":"video","pts_time":"5.400000","flags":"K__"},
+		{"codec_type":"video","pts_time":"7.400000","flags":"K__"},
+		{"codec_type":"video","pts_time":"9.400000","flags":"K__"}]}`
+)
+
+func trimFake(encoders string) *fftest.Executor {
+	fake := fftest.NewExecutor()
+	fake.On("-show_streams").Stdout(trimProbeJSON)
+	fake.On("-show_packets").Stdout(trimPacketsJSON)
+	fake.On("-hide_banner -encoders").Stdout(encoders)
+	fake.On() // 其余能力检测和 ffmpeg 本身都成功退出
+	return fake
+}
+
+func ffmpegRuns(fake *fftest.Executor) []string {
+	var out []string
+	for _, c := range fake.Calls() {
+		line := " " + strings.Join(c.Args, " ") + " "
+		if strings.Contains(line, " -i ") && !strings.Contains(line, "-show_") {
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+func TestTrimSmartPlan(t *testing.T) {
+	fake := trimFake(" V....D libx264              H.264\n A....D aac                  AAC\n")
+	tool := &ffmpeg.FFmpegTool{Executor: fake}
+
+	res, err := tool.Trim(context.Background(), "in.mp4", "out.mp4", time.Second, 7*time.Second,
+		ffmpeg.TrimOptions{Mode: ffmpeg.TrimSmart}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Mode != ffmpeg.TrimSmart || res.Reencoded != 2*time.Second {
+		t.Fatalf("result = %+v, want smart with 2s re-encoded", res)
+	}
+
+	runs := ffmpegRuns(fake)
+	want := [][]string{
+		// 关键帧时间减掉 start_time 后，切点两侧是 2s 和 6s
+		{" -ss 1 -t 1 -i in.mp4 ", " -c:v libx264 ", " -profile:v high ", " -level 4.0 ", " -pix_fmt yuv420p ",
+			" -color_range tv ", " -color_primaries bt709 ", " -f mpegts "},
+		{" -ss 2 -t 4 -i in.mp4 ", " -c:v copy -bsf:v h264_mp4toannexb ", " -f mpegts "},
+		{" -ss 6 -t 1 -i in.mp4 ", " -c:v libx264 ", " -f mpegts "},
+		{" -f concat ", " -ss 1 -t 6 -i in.mp4 ", " -map 1:a:0 ", " -c:a aac ", " -tag:v avc3 ", " -video_track_timescale 12800 ", " out.mp4 "},
+	}
+	if len(runs) != len(want) {
+		t.Fatalf("ffmpeg runs:\n%s", strings.Join(runs, "\n"))
+	}
+	for i, parts := range want {
+		for _, p := range parts {
+			if !strings.Contains(runs[i], p) {
+				t.Errorf("run %d = %q, missing %q", i, runs[i], p)
+			}
+		}
+	}
+	if strings.Contains(runs[0], " -c:a ") || strings.Contains(runs[1], "0:a") {
+		t.Errorf("video parts must not carry audio:\n%s\n%s", runs[0], runs[1])
+	}
+}
+
+func TestTrimSmartFallsBackBeforeEncoding(t *testing.T) {
+	fake := trimFake(" A....D aac                  AAC\n")
+	tool := &ffmpeg.FFmpegTool{Executor: fake}
+
+	res, err := tool.Trim(context.Background(), "in.mp4", "out.mp4", time.Second, 7*time.Second,
+		ffmpeg.TrimOptions{Mode: ffmpeg.TrimSmart}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Mode != ffmpeg.TrimExact || !strings.Contains(res.Reason, "libx264") {
+		t.Errorf("result = %+v, want exact because libx264 is missing", res)
+	}
+	runs := ffmpegRuns(fake)
+	if len(runs) != 1 || strings.Contains(runs[0], "mpegts") {
+		t.Errorf("ffmpeg runs = %q, want a single exact trim", runs)
+	}
+}
+
+// 需要真实的 ffmpeg / ffprobe：生成一个源文件，smart 截取后完整解码一遍
+func TestTrimSmartDecodes(t *testing.T) {
+	for _, bin := range []string{"ffmpeg", "ffprobe"} {
+		if _, err := exec.LookPath(bin); err != nil {
+			t.Skipf("%s not installed", bin)
+		}
+	}
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.mp4")
+	gen := exec.Command("ffmpeg", "-v", "error", "-y",
+		"-f", "lavfi", "-i", "testsrc2=duration=10:size=320x240:rate=25",
+		"-f", "lavfi", "-i", "sine=frequency=440:duration=10",
+		"-c:v", "libx264", "-profile:v", "main", "-g", "50", "-bf", "2", "-pix_fmt", "yuv420p",
+		"-c:a", "aac", "-shortest", src)
+	if out, err := gen.CombinedOutput(); err != nil {
+		t.Skipf("cannot generate source (needs libx264): %v: %s", err, out)
+	}
+
+	tool := ffmpeg.NewDefaultFFmpeg()
+	ctx := context.Background()
+	for _, ext := range []string{".mp4", ".mkv"} {
+		out := filepath.Join(dir, "out"+ext)
+		res, err := tool.Trim(ctx, src, out, 1300*time.Millisecond, 7700*time.Millisecond,
+			ffmpeg.TrimOptions{Mode: ffmpeg.TrimSmart}, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", ext, err)
+		}
+		if res.Mode != ffmpeg.TrimSmart {
+			t.Fatalf("%s: fell back: %s", ext, res.Reason)
+		}
+
+		var stderr bytes.Buffer
+		dec := exec.Command("ffmpeg", "-v", "error", "-xerror", "-i", out, "-f", "null", "-")
+		dec.Stderr = &stderr
+		if err := dec.Run(); err != nil || stderr.Len() > 0 {
+			t.Errorf("%s: decode failed: %v: %s", ext, err, stderr.String())
+		}
+
+		info, err := ffprobe.NewDefaultTool().Probe(ctx, out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		secs, _ := strconv.ParseFloat(info.Format.Duration, 64)
+		if d := time.Duration(secs * float64(time.Second)); d < 6300*time.Millisecond || d > 6500*time.Millisecond {
+			t.Errorf("%s: duration = %s, want about 6.4s", ext, d)
+		}
+	}
+}
+
+func TestTrimExactToEnd(t *testing.T) {
+	fake := trimFake("")
+	tool := &ffmpeg.FFmpegTool{Executor: fake}
+
+	res, err := tool.Trim(context.Background(), "in.mp4", "out.mp4", time.Second, 0,
+		ffmpeg.TrimOptions{Mode: ffmpeg.TrimExact}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Reencoded != 9*time.Second {
+		t.Errorf("Reencoded = %s, want 9s (probed 10s - start 1s)", res.Reencoded)
+	}
+	runs := ffmpegRuns(fake)
+	if len(runs) != 1 || strings.Contains(runs[0], " -t ") {
+		t.Errorf("ffmpeg runs = %q, want one trim without -t", runs)
+	}
+}
+
+func TestTrimSmartNoAudioIgnoresAudioCodec(t *testing.T) {
+	fake := fftest.NewExecutor()
+	fake.On("-show_streams").Stdout(`{"streams":[
+		{"index":0,"codec_name":"h264","codec_type":"video","profile":"High","level":40,"pix_fmt":"yuv420p"}],
+		"format":{"start_time":"1.400000","duration":"10.000000"}}`)
+	fake.On("-show_packets").Stdout(trimPacketsJSON)
+	fake.On("-hide_banner -encoders").Stdout(" V....D libx264              H.264\n")
+	fake.On()
+	tool := &ffmpeg.FFmpegTool{Executor: fake}
+
+	res, err := tool.Trim(context.Background(), "in.mp4", "out.mp4", time.Second, 7*time.Second,
+		ffmpeg.TrimOptions{Mode: ffmpeg.TrimSmart, AudioCodec: "libopus"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Mode != ffmpeg.TrimSmart {
+		t.Errorf("result = %+v, want smart: the unused audio encoder must not force a fallback", res)
+	}
+	for _, r := range ffmpegRuns(fake) {
+		if strings.Contains(r, "libopus") {
+			t.Errorf("video-only trim uses the audio encoder: %s", r)
+		}
+	}
+}
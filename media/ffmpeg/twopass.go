@@ -0,0 +1,102 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// TwoPassOptions 两遍目标码率（ABR）编码
+type TwoPassOptions struct {
+	VideoCodec   string // 默认 libx264；libx265 走 -x265-params pass=N
+	VideoBitrate string // 必填，例如 "4000k"
+	MaxRate      string // 可选，平台有峰值码率限制时设置
+	BufSize      string // 可选，默认 2 倍 MaxRate
+	Preset       string // 默认 medium
+
+	AudioCodec   string // 默认 aac
+	AudioBitrate string // 默认 128k
+
+	FastStart bool // mp4 输出时加 +faststart
+}
+
+// EncodeTwoPass 第一遍输出到 null 只生成 passlog，第二遍按 passlog 分配码率输出。
+// passlog 写在私有临时目录里，结束后删除，多个任务并发不会互相覆盖。
+// 进度回调里 Pass=1/2、Passes=2。
+func (t *FFmpegTool) EncodeTwoPass(
+	ctx context.Context,
+	input, output string,
+	opt TwoPassOptions,
+	onProgress func(p FFmpegProgress) error,
+) (FFmpegProgress, error) {
+	if opt.VideoBitrate == "" {
+		return FFmpegProgress{}, errors.New("two-pass: VideoBitrate is required")
+	}
+	if opt.VideoCodec == "" {
+		opt.VideoCodec = "libx264"
+	}
+	if opt.Preset == "" {
+		opt.Preset = "medium"
+	}
+	if opt.AudioCodec == "" {
+		opt.AudioCodec = "aac"
+	}
+	if opt.AudioBitrate == "" {
+		opt.AudioBitrate = "128k"
+	}
+
+	dir, err := os.MkdirTemp("", "ffmpeg2pass-*")
+	if err != nil {
+		return FFmpegProgress{}, fmt.Errorf("two-pass: create passlog dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	logPrefix := filepath.Join(dir, "passlog")
+
+	pass1 := twoPassBase(input, opt, 1, logPrefix).
+		AppendArgs("-an", "-f", "null").
+		Output("-")
+	if _, err := t.RunWithProgress(ctx, pass1, passProgress(1, 2, onProgress)); err != nil {
+		return FFmpegProgress{}, fmt.Errorf("two-pass pass 1: %w", err)
+	}
+
+	pass2 := twoPassBase(input, opt, 2, logPrefix).
+		AudioCodec(opt.AudioCodec).
+		AppendArgs("-b:a", opt.AudioBitrate)
+	if opt.FastStart {
+		pass2.MovFlagsFastStart()
+	}
+	pass2.Output(output)
+
+	last, err := t.RunWithProgress(ctx, pass2, passProgress(2, 2, onProgress))
+	if err != nil {
+		return last, fmt.Errorf("two-pass pass 2: %w", err)
+	}
+	return last, nil
+}
+
+func twoPassBase(input string, opt TwoPassOptions, pass int, logPrefix string) *FFmpegCommand {
+	cmd := NewFFmpegCommand().
+		HideBanner().
+		LogLevel("error").
+		Input(input).
+		VideoCodec(opt.VideoCodec).
+		Preset(opt.Preset).
+		AppendArgs("-b:v", opt.VideoBitrate)
+	if opt.MaxRate != "" {
+		bufSize := opt.BufSize
+		if bufSize == "" {
+			bufSize = doubleBitrate(opt.MaxRate)
+		}
+		cmd.AppendArgs("-maxrate", opt.MaxRate, "-bufsize", bufSize)
+	}
+	if opt.VideoCodec == "libx265" {
+		// libx265 不认 -pass / -passlogfile
+		cmd.RequireLibrary("libx265", "", "two-pass x265")
+		cmd.AppendArgs("-x265-params", "pass="+itoa(pass)+":stats="+logPrefix+".x265.log")
+	} else {
+		cmd.AppendArgs("-pass", itoa(pass), "-passlogfile", logPrefix)
+	}
+	return cmd
+}
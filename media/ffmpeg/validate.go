@@ -0,0 +1,459 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type Severity int
+
+const (
+	SeverityWarning Severity = iota // ffmpeg 能跑，但结果多半不是想要的
+	SeverityError                   // ffmpeg 一定会失败
+)
+
+func (s Severity) String() string {
+	if s == SeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
+// DiagCode 诊断类别，便于程序判断
+type DiagCode string
+
+const (
+	DiagNoInput         DiagCode = "no-input"
+	DiagNoOutput        DiagCode = "no-output"
+	DiagUnknownEncoder  DiagCode = "unknown-encoder"
+	DiagUnknownDecoder  DiagCode = "unknown-decoder"
+	DiagUnknownFilter   DiagCode = "unknown-filter"
+	DiagUnknownMuxer    DiagCode = "unknown-muxer"
+	DiagEncodeWithCopy  DiagCode = "encode-option-with-copy" // -crf / -b:v 等和 copy 同时出现，ffmpeg 会忽略它们
+	DiagFilterWithCopy  DiagCode = "filter-with-copy"        // 对 stream copy 的流加滤镜
+	DiagSeekAfterInput  DiagCode = "seek-after-input"        // 输入已用 -ss 快速 seek，-i 之后又写了 -ss
+	DiagSlowSeek        DiagCode = "slow-seek"               // -ss 写在 -i 之后：解码再丢帧，长文件很慢
+	DiagTrailingOptions DiagCode = "trailing-options"        // 最后一个输出之后还有选项
+	DiagRequirement     DiagCode = "requirement"             // 不满足 Require 声明的版本/库
+)
+
+// Diagnostic 一条检查结果；Input / Output 为相关输入输出的序号，不相关为 -1
+type Diagnostic struct {
+	Severity Severity
+	Code     DiagCode
+	Input    int
+	Output   int
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	where := ""
+	switch {
+	case d.Output >= 0:
+		where = fmt.Sprintf(" output #%d", d.Output)
+	case d.Input >= 0:
+		where = fmt.Sprintf(" input #%d", d.Input)
+	}
+	return fmt.Sprintf("%s[%s]%s: %s", d.Severity, d.Code, where, d.Message)
+}
+
+// ErrInvalidCommand 校验出 SeverityError 级别的问题，errors.As 到 Diagnostics 取全部结果
+var ErrInvalidCommand = errors.New("ffmpeg: invalid command")
+
+type Diagnostics []Diagnostic
+
+// HasErrors 是否有 SeverityError
+func (ds Diagnostics) HasErrors() bool {
+	for _, d := range ds {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Err 有错误级别的诊断时返回非 nil（自身，可 errors.Is(err, ErrInvalidCommand)），只有警告返回 nil
+func (ds Diagnostics) Err() error {
+	if !ds.HasErrors() {
+		return nil
+	}
+	return ds
+}
+
+// Error 只列出 SeverityError 的诊断；没有错误时为空串
+func (ds Diagnostics) Error() string {
+	var lines []string
+	for _, d := range ds {
+		if d.Severity == SeverityError {
+			lines = append(lines, d.String())
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return "ffmpeg: invalid command:\n" + strings.Join(lines, "\n")
+}
+
+func (ds Diagnostics) Is(target error) bool { return target == ErrInvalidCommand }
+
+// Rule 一条检查规则；caps 可能为 nil（不做和编译能力相关的检查）
+type Rule func(c *FFmpegCommand, caps *Capabilities) []Diagnostic
+
+// DefaultRules Validate 使用的规则，可以追加自定义规则后传给 ValidateWith
+var DefaultRules = []Rule{
+	RuleInputsOutputs,
+	RuleCapabilities,
+	RuleCopyConflicts,
+	RuleSeekAfterInput,
+}
+
+// Validate 用 DefaultRules 检查命令，不启动进程；caps 为 nil 时跳过编码器/滤镜/封装格式检查
+func (c *FFmpegCommand) Validate(caps *Capabilities) Diagnostics {
+	return c.ValidateWith(caps, DefaultRules...)
+}
+
+func (c *FFmpegCommand) ValidateWith(caps *Capabilities, rules ...Rule) Diagnostics {
+	var out Diagnostics
+	for _, r := range rules {
+		out = append(out, r(c, caps)...)
+	}
+	return out
+}
+
+// Validate 用检测到的编译能力和 DefaultRules 检查命令，同时检查 Require 声明的版本要求。
+// 返回全部诊断；error 只表示检测本身失败（找不到 ffmpeg 等）
+func (t *FFmpegTool) Validate(ctx context.Context, cmd *FFmpegCommand) (Diagnostics, error) {
+	caps, err := t.Capabilities(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ds := cmd.Validate(caps)
+	if err := t.CheckRequirements(ctx, cmd); err != nil {
+		var re *RequirementError
+		for _, e := range unwrapAll(err) {
+			if errors.As(e, &re) {
+				ds = append(ds, Diagnostic{Severity: SeverityError, Code: DiagRequirement, Input: -1, Output: -1, Message: re.Error()})
+			} else {
+				return ds, err
+			}
+		}
+	}
+	return ds, nil
+}
+
+func unwrapAll(err error) []error {
+	if j, ok := err.(interface{ Unwrap() []error }); ok {
+		return j.Unwrap()
+	}
+	return []error{err}
+}
+
+func diag(sev Severity, code DiagCode, in, out int, format string, args ...any) Diagnostic {
+	return Diagnostic{Severity: sev, Code: code, Input: in, Output: out, Message: fmt.Sprintf(format, args...)}
+}
+
+// RuleInputsOutputs 至少一个输入和输出、输出路径非空、没有被忽略的尾随选项
+func RuleInputsOutputs(c *FFmpegCommand, _ *Capabilities) []Diagnostic {
+	var ds []Diagnostic
+	if len(c.inputs) == 0 && !hasOpt(c.pending, "-i") {
+		ds = append(ds, diag(SeverityError, DiagNoInput, -1, -1, "command has no input"))
+	}
+	// 全部用 AppendArgs 拼的旧式命令：按选项是否带值跳过参数，剩下不是选项的就是输出
+	if len(c.outputs) == 0 && !hasBareArg(c.pending) {
+		ds = append(ds, diag(SeverityError, DiagNoOutput, -1, -1, "command has no output; call Output / AddOutput"))
+	}
+	for _, o := range c.outputs {
+		if strings.TrimSpace(o.Path) == "" {
+			ds = append(ds, diag(SeverityError, DiagNoOutput, -1, o.index, "output path is empty"))
+		}
+	}
+	if trailing := append(append([]string(nil), c.pending...), filterArgs(c.vf, c.af)...); len(c.outputs) > 0 && len(trailing) > 0 {
+		ds = append(ds, diag(SeverityWarning, DiagTrailingOptions, -1, -1,
+			"options after the last output are ignored by ffmpeg: %s", strings.Join(trailing, " ")))
+	}
+	return ds
+}
+
+// hasBareArg args 里是否有不属于任何选项的参数（输出路径）
+func hasBareArg(args []string) bool {
+	for i := 0; i < len(args); i++ {
+		if !strings.HasPrefix(args[i], "-") || args[i] == "-" {
+			return true
+		}
+		if takesValue, _ := optionArity(args[i]); takesValue {
+			i++
+		}
+	}
+	return false
+}
+
+// RuleCapabilities 编码器、解码器、滤镜、封装格式都已编译进 ffmpeg
+func RuleCapabilities(c *FFmpegCommand, caps *Capabilities) []Diagnostic {
+	if caps == nil {
+		return nil
+	}
+	var ds []Diagnostic
+	for _, in := range c.inputs {
+		for _, sc := range streamCodecs(in.opts) {
+			if sc.codec != "copy" && !caps.HasDecoder(sc.codec) {
+				ds = append(ds, diag(SeverityError, DiagUnknownDecoder, in.index, -1, "decoder %q is not available", sc.codec))
+			}
+		}
+	}
+	for _, o := range c.outputs {
+		for _, sc := range streamCodecs(o.opts) {
+			if sc.codec != "copy" && !caps.HasEncoder(sc.codec) {
+				ds = append(ds, diag(SeverityError, DiagUnknownEncoder, -1, o.index, "encoder %q is not available", sc.codec))
+			}
+		}
+		if f := optValue(o.opts, "-f"); f != "" && !caps.HasMuxer(f) {
+			ds = append(ds, diag(SeverityError, DiagUnknownMuxer, -1, o.index, "muxer %q is not available", f))
+		}
+		for _, name := range outputFilterNames(o) {
+			if !caps.HasFilter(name) {
+				ds = append(ds, diag(SeverityError, DiagUnknownFilter, -1, o.index, "filter %q is not available", name))
+			}
+		}
+	}
+	if !c.graph.Empty() {
+		for _, ch := range c.graph.Chains() {
+			for _, name := range chainFilterNames(ch) {
+				if !caps.HasFilter(name) {
+					ds = append(ds, diag(SeverityError, DiagUnknownFilter, -1, -1, "filter %q in -filter_complex is not available", name))
+				}
+			}
+		}
+	}
+	if s := optValue(c.global, "-filter_complex"); s != "" {
+		for _, name := range filterNames(s) {
+			if !caps.HasFilter(name) {
+				ds = append(ds, diag(SeverityError, DiagUnknownFilter, -1, -1, "filter %q in -filter_complex is not available", name))
+			}
+		}
+	}
+	return ds
+}
+
+// 和 copy 冲突的编码参数（按流类型）
+var encodeOnlyOpts = []struct {
+	typ  string
+	opts []string
+}{
+	{"v", []string{"-crf", "-qp", "-b:v", "-maxrate", "-bufsize", "-preset", "-tune", "-profile:v", "-pix_fmt", "-r", "-s", "-g"}},
+	{"a", []string{"-b:a", "-ar", "-ac", "-q:a"}},
+}
+
+// RuleCopyConflicts stream copy 的流带编码参数会被 ffmpeg 忽略（警告）；加滤镜则直接报错
+func RuleCopyConflicts(c *FFmpegCommand, _ *Capabilities) []Diagnostic {
+	var ds []Diagnostic
+	for _, o := range c.outputs {
+		copied := map[string]bool{}
+		for _, sc := range streamCodecs(o.opts) {
+			if sc.codec == "copy" {
+				if sc.stream == "" {
+					copied["v"], copied["a"] = true, true
+				} else {
+					copied[sc.stream[:1]] = true
+				}
+			} else if sc.stream != "" {
+				// -c copy -c:v libx264：后面的按流设置覆盖
+				copied[sc.stream[:1]] = false
+			}
+		}
+		for _, e := range encodeOnlyOpts {
+			if !copied[e.typ] {
+				continue
+			}
+			for _, name := range e.opts {
+				if hasOpt(o.opts, name) {
+					ds = append(ds, diag(SeverityWarning, DiagEncodeWithCopy, -1, o.index,
+						"%s has no effect on a stream-copied %s stream", name, streamTypeName(e.typ)))
+				}
+			}
+		}
+		if copied["v"] && (o.vf.Len() > 0 || hasOpt(o.opts, "-vf") || hasOpt(o.opts, "-filter:v")) {
+			ds = append(ds, diag(SeverityError, DiagFilterWithCopy, -1, o.index, "video filter on a stream-copied video stream"))
+		}
+		if copied["a"] && (o.af.Len() > 0 || hasOpt(o.opts, "-af") || hasOpt(o.opts, "-filter:a")) {
+			ds = append(ds, diag(SeverityError, DiagFilterWithCopy, -1, o.index, "audio filter on a stream-copied audio stream"))
+		}
+	}
+	return ds
+}
+
+// RuleSeekAfterInput 检查写在 -i 之后（输出侧）的 -ss：
+// 对应输入已经用 -ss 快速 seek 时，输出侧的偏移会从 seek 后的位置再算一次，是错误；
+// 否则只是慢（先解码再丢帧），给出警告
+func RuleSeekAfterInput(c *FFmpegCommand, _ *Capabilities) []Diagnostic {
+	fastSeek := -1
+	for _, in := range c.inputs {
+		if hasOpt(in.opts, "-ss") {
+			fastSeek = in.index
+			break
+		}
+	}
+	var ds []Diagnostic
+	for _, o := range c.outputs {
+		if hasOpt(o.opts, "-ss") {
+			ds = append(ds, seekAfterInput(fastSeek, o.index))
+		}
+	}
+	// 全部用 AppendArgs 拼的旧式命令：按参数顺序找 -i 之后的 -ss
+	if len(c.outputs) == 0 {
+		in, seekNext := -1, false
+		for i := 0; i+1 < len(c.pending); i++ {
+			switch c.pending[i] {
+			case "-ss":
+				if in >= 0 || len(c.inputs) > 0 {
+					ds = append(ds, seekAfterInput(fastSeek, -1))
+				} else {
+					seekNext = true
+				}
+				i++
+			case "-i":
+				if seekNext && fastSeek < 0 {
+					fastSeek = in + 1
+				}
+				seekNext = false
+				in++
+				i++
+			default:
+				if takesValue, _ := optionArity(c.pending[i]); takesValue && strings.HasPrefix(c.pending[i], "-") {
+					i++
+				}
+			}
+		}
+	}
+	return ds
+}
+
+func seekAfterInput(fastSeek, output int) Diagnostic {
+	if fastSeek >= 0 {
+		return diag(SeverityError, DiagSeekAfterInput, fastSeek, output,
+			"-ss after -i is counted from the position the input was already fast-seeked to; put the whole offset before -i")
+	}
+	return diag(SeverityWarning, DiagSlowSeek, -1, output,
+		"-ss after -i decodes and discards everything before the offset; put it before -i for a fast seek")
+}
+
+func streamTypeName(t string) string {
+	if t == "a" {
+		return "audio"
+	}
+	return "video"
+}
+
+type streamCodec struct {
+	stream string // "" = 全部流，"v" / "a" / "s" / "v:0" ...
+	codec  string
+}
+
+// streamCodecs 找出 -c / -c:v / -vcodec / -codec:a:0 等设置
+func streamCodecs(opts []string) []streamCodec {
+	var out []streamCodec
+	for i := 0; i+1 < len(opts); i++ {
+		k := opts[i]
+		var stream string
+		switch {
+		case k == "-c" || k == "-codec":
+		case k == "-vcodec":
+			stream = "v"
+		case k == "-acodec":
+			stream = "a"
+		case k == "-scodec":
+			stream = "s"
+		case strings.HasPrefix(k, "-c:"):
+			stream = k[3:]
+		case strings.HasPrefix(k, "-codec:"):
+			stream = k[7:]
+		default:
+			continue
+		}
+		out = append(out, streamCodec{stream: stream, codec: opts[i+1]})
+		i++
+	}
+	return out
+}
+
+// optValue 最后一次出现的 name 的值
+func optValue(opts []string, name string) string {
+	v := ""
+	for i := 0; i+1 < len(opts); i++ {
+		if opts[i] == name {
+			v = opts[i+1]
+		}
+	}
+	return v
+}
+
+func outputFilterNames(o *OutputSpec) []string {
+	names := append(chainFilterNames(o.vf), chainFilterNames(o.af)...)
+	for _, k := range []string{"-vf", "-af", "-filter:v", "-filter:a"} {
+		if s := optValue(o.opts, k); s != "" {
+			names = append(names, filterNames(s)...)
+		}
+	}
+	return names
+}
+
+func chainFilterNames(ch *FilterChain) []string {
+	if ch.Len() == 0 {
+		return nil
+	}
+	var names []string
+	for _, f := range ch.Filters() {
+		if f.raw != "" {
+			names = append(names, filterNames(f.raw)...)
+		} else if f.Name != "" {
+			names = append(names, f.Name)
+		}
+	}
+	return names
+}
+
+// filterNames 粗略地从滤镜描述里取出滤镜名：按未转义的 , ; 切分，去掉 [label] 和 = 之后的参数
+func filterNames(s string) []string {
+	var names []string
+	var cur strings.Builder
+	depth, quoted, escaped := 0, false, false
+	flush := func() {
+		f := strings.TrimSpace(cur.String())
+		cur.Reset()
+		for strings.HasPrefix(f, "[") {
+			i := strings.IndexByte(f, ']')
+			if i < 0 {
+				return
+			}
+			f = strings.TrimSpace(f[i+1:])
+		}
+		if i := strings.IndexAny(f, "=@["); i >= 0 {
+			f = f[:i]
+		}
+		if f = strings.TrimSpace(f); f != "" {
+			names = append(names, f)
+		}
+	}
+	for _, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '\'':
+			quoted = !quoted
+		case quoted:
+		case r == '[':
+			depth++
+		case r == ']':
+			depth--
+		case (r == ',' || r == ';') && depth == 0:
+			flush()
+			continue
+		}
+		cur.WriteRune(r)
+	}
+	flush()
+	return names
+}
@@ -0,0 +1,103 @@
+package ffmpeg
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/LingByte/LingConvert/media/locate"
+)
+
+func diagCodes(ds Diagnostics) []DiagCode {
+	out := make([]DiagCode, len(ds))
+	for i, d := range ds {
+		out[i] = d.Code
+	}
+	return out
+}
+
+func hasDiag(ds Diagnostics, code DiagCode, sev Severity) bool {
+	for _, d := range ds {
+		if d.Code == code && d.Severity == sev {
+			return true
+		}
+	}
+	return false
+}
+
+func TestValidate(t *testing.T) {
+	caps := &Capabilities{
+		Encoders: map[string]locate.Codec{"libx264": {Name: "libx264"}, "aac": {Name: "aac"}},
+		Filters:  map[string]locate.FilterInfo{"scale": {Name: "scale"}},
+		Muxers:   map[string]locate.Format{"mp4": {Name: "mp4"}},
+	}
+	tests := []struct {
+		name  string
+		build func() *FFmpegCommand
+		code  DiagCode
+		sev   Severity
+	}{
+		{"unknown encoder", func() *FFmpegCommand {
+			return NewFFmpegCommand().Input("in.mp4").VideoCodec("libx265").Output("out.mp4")
+		}, DiagUnknownEncoder, SeverityError},
+		{"crf with copy", func() *FFmpegCommand {
+			return NewFFmpegCommand().Input("in.mp4").CopyVideo().CRF(23).Output("out.mp4")
+		}, DiagEncodeWithCopy, SeverityWarning},
+		{"filter on copy", func() *FFmpegCommand {
+			return NewFFmpegCommand().Input("in.mp4").CopyVideo().Scale(640, -2).Output("out.mp4")
+		}, DiagFilterWithCopy, SeverityError},
+		{"missing output", func() *FFmpegCommand {
+			return NewFFmpegCommand().Input("in.mp4").VideoCodec("libx264")
+		}, DiagNoOutput, SeverityError},
+		{"empty output path", func() *FFmpegCommand {
+			return NewFFmpegCommand().Input("in.mp4").Output("")
+		}, DiagNoOutput, SeverityError},
+		{"ss after fast-seek input", func() *FFmpegCommand {
+			return NewFFmpegCommand().StartAt(10).Input("in.mp4").StartAt(2).Output("out.mp4")
+		}, DiagSeekAfterInput, SeverityError},
+		{"ss after fast-seek input, legacy chain", func() *FFmpegCommand {
+			return NewFFmpegCommand().AppendArgs("-ss", "10", "-i", "in.mp4", "-ss", "2", "out.mp4")
+		}, DiagSeekAfterInput, SeverityError},
+		{"slow seek", func() *FFmpegCommand {
+			return NewFFmpegCommand().Input("in.mp4").StartAt(2).Output("out.mp4")
+		}, DiagSlowSeek, SeverityWarning},
+		{"trailing filter", func() *FFmpegCommand {
+			return NewFFmpegCommand().Input("in.mp4").Output("out.mp4").Scale(10, 10)
+		}, DiagTrailingOptions, SeverityWarning},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ds := tt.build().Validate(caps)
+			if !hasDiag(ds, tt.code, tt.sev) {
+				t.Errorf("diagnostics = %v, want %s %s", diagCodes(ds), tt.sev, tt.code)
+			}
+		})
+	}
+}
+
+func TestValidateClean(t *testing.T) {
+	cmd := NewFFmpegCommand().StartAt(10).Input("in.mp4").VideoCodec("libx264").Scale(640, -2).Output("out.mp4")
+	if ds := cmd.Validate(nil); len(ds) != 0 {
+		t.Errorf("diagnostics = %v, want none", ds)
+	}
+}
+
+func TestDiagnosticsError(t *testing.T) {
+	warn := Diagnostic{Severity: SeverityWarning, Code: DiagSlowSeek, Input: -1, Output: 0, Message: "slow"}
+	fail := Diagnostic{Severity: SeverityError, Code: DiagNoInput, Input: -1, Output: -1, Message: "no input"}
+
+	if got := (Diagnostics{}).Error(); got != "" {
+		t.Errorf("empty Error() = %q", got)
+	}
+	if ds := (Diagnostics{warn}); ds.Error() != "" || ds.Err() != nil {
+		t.Errorf("warnings only: Error() = %q, Err() = %v", ds.Error(), ds.Err())
+	}
+	ds := Diagnostics{warn, fail}
+	msg := ds.Error()
+	if !strings.Contains(msg, "no input") || strings.Contains(msg, "slow") {
+		t.Errorf("Error() = %q, want only the error item", msg)
+	}
+	if !errors.Is(ds.Err(), ErrInvalidCommand) {
+		t.Errorf("Err() = %v, want ErrInvalidCommand", ds.Err())
+	}
+}
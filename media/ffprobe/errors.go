@@ -0,0 +1,24 @@
+package ffprobe
+
+import "github.com/LingByte/LingConvert/media/fferr"
+
+// Error 是 ffprobe 执行失败时返回的错误，可用 errors.As 取出退出码、stderr 末尾和命令行
+type Error = fferr.Error
+
+// 失败分类，用 errors.Is 判断
+var (
+	ErrInputNotFound    = fferr.ErrInputNotFound
+	ErrPermissionDenied = fferr.ErrPermissionDenied
+	ErrInvalidData      = fferr.ErrInvalidData
+	ErrUnknownEncoder   = fferr.ErrUnknownEncoder
+	ErrUnknownDecoder   = fferr.ErrUnknownDecoder
+	ErrUnsupportedCodec = fferr.ErrUnsupportedCodec
+	ErrInvalidArgument  = fferr.ErrInvalidArgument
+	ErrFilter           = fferr.ErrFilter
+	ErrOutputExists     = fferr.ErrOutputExists
+	ErrNetwork          = fferr.ErrNetwork
+	ErrTimeout          = fferr.ErrTimeout
+	ErrCanceled         = fferr.ErrCanceled
+	ErrStoppedEarly     = fferr.ErrStoppedEarly
+	ErrResourceLimit    = fferr.ErrResourceLimit
+)
@@ -1,28 +1,24 @@
 package ffprobe
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"os/exec"
+
+	"github.com/LingByte/LingConvert/media/fferr"
+	"github.com/LingByte/LingConvert/media/proc"
 )
 
 func (t *Tool) runFFProbeJSON(ctx context.Context, args []string, out any) error {
-	if err := t.ensureReady(ctx); err != nil {
+	ffprobeBin, err := t.binary(ctx)
+	if err != nil {
 		return err
 	}
 
-	t.mu.Lock()
-	ffprobeBin := t.resolvedPath
-	t.mu.Unlock()
-
-	cmd := exec.CommandContext(ctx, ffprobeBin, args...)
-	b, err := cmd.Output()
+	b, err := t.output(ctx, ffprobeBin, args)
 	if err != nil {
-		if ee, ok := err.(*exec.ExitError); ok {
-			return fmt.Errorf("ffprobe failed: %w; stderr=%s", err, string(ee.Stderr))
-		}
-		return fmt.Errorf("ffprobe exec error: %w", err)
+		return err
 	}
 	if out == nil {
 		return nil
@@ -33,6 +29,29 @@ func (t *Tool) runFFProbeJSON(ctx context.Context, args []string, out any) error
 	return nil
 }
 
+// output 执行 ffprobe 并返回 stdout
+func (t *Tool) output(ctx context.Context, bin string, args []string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	err := proc.Run(ctx, t.executor(), proc.Spec{
+		Path:   bin,
+		Args:   args,
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Limits: t.Limits,
+	})
+	if err != nil {
+		return nil, fferr.New("ffprobe", append([]string{bin}, args...), stderr.String(), err, ctx.Err())
+	}
+	return stdout.Bytes(), nil
+}
+
+func (t *Tool) executor() proc.Executor {
+	if t.Executor != nil {
+		return t.Executor
+	}
+	return proc.OS
+}
+
 type Packet struct {
 	CodecType   string `json:"codec_type"`
 	StreamIndex int    `json:"stream_index"`
@@ -1,15 +1,15 @@
 package ffprobe
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
-	"os/exec"
-	"strings"
+	"os"
 	"sync"
 	"time"
+
+	"github.com/LingByte/LingConvert/media/locate"
+	"github.com/LingByte/LingConvert/media/proc"
 )
 
 // FFProbeJSON for ffprobe -show_format -show_streams -of json output
@@ -43,6 +43,11 @@ type Stream struct {
 	Width          int               `json:"width"`
 	Height         int               `json:"height"`
 	PixFmt         string            `json:"pix_fmt"`
+	Level          int               `json:"level"` // h264: 40 = 4.0；hevc: 120 = 4.0；未知为 -99
+	ColorRange     string            `json:"color_range"`
+	ColorSpace     string            `json:"color_space"`
+	ColorTransfer  string            `json:"color_transfer"`
+	ColorPrimaries string            `json:"color_primaries"`
 	RFrameRate     string            `json:"r_frame_rate"`   // e.g. "30000/1001"
 	AvgFrameRate   string            `json:"avg_frame_rate"` // e.g. "30000/1001"
 	TimeBase       string            `json:"time_base"`
@@ -59,12 +64,17 @@ type Stream struct {
 type Tool struct {
 	FFProbePath string        // default "ffprobe" or absolute path
 	Timeout     time.Duration // default 10s~30s
+	// 进程组 / nice / rlimit 限制（Threads 对 ffprobe 无效）
+	Limits proc.Limits
+	// 启动进程的后端，nil = proc.OS；测试时可换成 media/testing 的假实现
+	Executor proc.Executor
+
+	// NextTo ffmpeg 的名字或路径；FFProbePath 没有显式配置时优先用它同目录下的 ffprobe，
+	// 为空时参考 LINGCONVERT_FFMPEG
+	NextTo string
 
-	mu           sync.Mutex
-	checked      bool   // whether ffprobe is already checked
-	resolvedPath string // absolute path resolved by LookPath
-	version      string // detected version, best-effort
-	checkErr     error  // cached check error
+	mu  sync.Mutex
+	loc *locate.Locator
 }
 
 func NewDefaultTool() *Tool {
@@ -74,98 +84,52 @@ func NewDefaultTool() *Tool {
 	}
 }
 
-// ensureReady makes sure ffprobe exists and is runnable.
-// It caches results so it only runs the expensive checks once per Tool instance.
-func (t *Tool) ensureReady(ctx context.Context) error {
+// locator 懒创建，FFProbePath / NextTo / Executor 在第一次使用后再修改不会生效
+func (t *Tool) locator() *locate.Locator {
 	t.mu.Lock()
-	if t.checked {
-		err := t.checkErr
-		t.mu.Unlock()
-		return err
-	}
-	t.mu.Unlock()
-
-	// Do the actual check without holding the lock
-	ffprobePath := t.FFProbePath
-	if ffprobePath == "" {
-		ffprobePath = "ffprobe"
-	}
-
-	resolved, err := exec.LookPath(ffprobePath)
-	if err != nil {
-		t.mu.Lock()
-		t.checked = true
-		t.checkErr = fmt.Errorf("ffprobe not found (FFProbePath=%q): %w", ffprobePath, err)
-		t.mu.Unlock()
-		return t.checkErr
-	}
-
-	timeout := t.Timeout
-	if timeout <= 0 {
-		timeout = 15 * time.Second
-	}
-
-	// Quick run "-version" to verify it executes; use smaller timeout to avoid hanging
-	cctx, cancel := context.WithTimeout(ctx, minDuration(timeout, 5*time.Second))
-	defer cancel()
-
-	cmd := exec.CommandContext(cctx, resolved, "-version")
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	runErr := cmd.Run()
-	if runErr != nil {
-		if errors.Is(cctx.Err(), context.DeadlineExceeded) {
-			err = fmt.Errorf("ffprobe check timed out (path=%q)", resolved)
-		} else {
-			err = fmt.Errorf("ffprobe exists but cannot run (path=%q): %w; stderr=%s",
-				resolved, runErr, strings.TrimSpace(stderr.String()))
+	defer t.mu.Unlock()
+	if t.loc == nil {
+		next := t.NextTo
+		if next == "" {
+			next = os.Getenv(locate.EnvFFmpeg)
+		}
+		t.loc = &locate.Locator{
+			Name:         "ffprobe",
+			Path:         t.FFProbePath,
+			Env:          locate.EnvFFprobe,
+			NextTo:       next,
+			Executor:     t.Executor,
+			CheckTimeout: minDuration(t.timeout(), locate.DefaultCheckTimeout),
 		}
-		t.mu.Lock()
-		t.checked = true
-		t.resolvedPath = resolved
-		t.checkErr = err
-		t.mu.Unlock()
-		return err
 	}
+	return t.loc
+}
 
-	ver := parseFFProbeVersion(stdout.String())
-	if ver == "" {
-		ver = "unknown"
-	}
+// binary 返回 ffprobe 的绝对路径；失败结果按退避间隔重试，不会永久缓存
+func (t *Tool) binary(ctx context.Context) (string, error) {
+	return t.locator().Resolve(ctx)
+}
 
-	t.mu.Lock()
-	t.checked = true
-	t.resolvedPath = resolved
-	t.version = ver
-	t.checkErr = nil
-	t.mu.Unlock()
-	return nil
+func (t *Tool) timeout() time.Duration {
+	if t.Timeout <= 0 {
+		return 15 * time.Second
+	}
+	return t.Timeout
 }
 
 // Version returns detected ffprobe version. It will auto-check on first call.
 func (t *Tool) Version(ctx context.Context) (string, error) {
-	if err := t.ensureReady(ctx); err != nil {
-		return "", err
-	}
-	t.mu.Lock()
-	defer t.mu.Unlock()
-	return t.version, nil
+	return t.locator().Version(ctx)
 }
 
 // Probe 执行 ffprobe 并返回解析后的结构体（会自动检测 ffprobe 一次）
 func (t *Tool) Probe(ctx context.Context, input string) (*FFProbeJSON, error) {
-	if err := t.ensureReady(ctx); err != nil {
+	ffprobeBin, err := t.binary(ctx)
+	if err != nil {
 		return nil, err
 	}
 
-	timeout := t.Timeout
-	if timeout <= 0 {
-		timeout = 15 * time.Second
-	}
-
-	cctx, cancel := context.WithTimeout(ctx, timeout)
+	cctx, cancel := context.WithTimeout(ctx, t.timeout())
 	defer cancel()
 
 	args := []string{
@@ -177,23 +141,9 @@ func (t *Tool) Probe(ctx context.Context, input string) (*FFProbeJSON, error) {
 		input,
 	}
 
-	// use resolvedPath to avoid PATH issues
-	t.mu.Lock()
-	ffprobeBin := t.resolvedPath
-	t.mu.Unlock()
-
-	cmd := exec.CommandContext(cctx, ffprobeBin, args...)
-
-	out, err := cmd.Output()
+	out, err := t.output(cctx, ffprobeBin, args)
 	if err != nil {
-		var ee *exec.ExitError
-		if errors.As(err, &ee) {
-			return nil, fmt.Errorf("ffprobe failed: %w; stderr=%s", err, string(ee.Stderr))
-		}
-		if errors.Is(cctx.Err(), context.DeadlineExceeded) {
-			return nil, fmt.Errorf("ffprobe timed out after %s", timeout)
-		}
-		return nil, fmt.Errorf("ffprobe exec error: %w", err)
+		return nil, err
 	}
 
 	var parsed FFProbeJSON
@@ -240,26 +190,6 @@ func (p *FFProbeJSON) FirstAudio() *Stream {
 	return nil
 }
 
-// parseFFProbeVersion tries to extract version from "ffprobe version x.y.z ..."
-func parseFFProbeVersion(s string) string {
-	lines := strings.Split(s, "\n")
-	if len(lines) == 0 {
-		return ""
-	}
-	origFirst := strings.TrimSpace(lines[0])
-	first := strings.ToLower(origFirst)
-	if !strings.Contains(first, "ffprobe version") {
-		return ""
-	}
-	origParts := strings.Fields(origFirst)
-	for i := 0; i < len(origParts)-1; i++ {
-		if strings.ToLower(origParts[i]) == "version" {
-			return origParts[i+1]
-		}
-	}
-	return ""
-}
-
 func minDuration(a, b time.Duration) time.Duration {
 	if a <= b {
 		return a
@@ -0,0 +1,232 @@
+package locate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/LingByte/LingConvert/media/proc"
+)
+
+// Codec 是 -encoders / -decoders 的一行
+type Codec struct {
+	Name        string
+	Type        string // "video" / "audio" / "subtitle" / "data" / "attachment"
+	Flags       string // 原始标记，例如 "V....D"
+	Description string
+}
+
+// FilterInfo 是 -filters 的一行
+type FilterInfo struct {
+	Name        string
+	Flags       string // T=timeline S=slice threading C=command
+	Inputs      string // 例如 "V" / "AA" / "N" / "|"
+	Outputs     string
+	Description string
+}
+
+// Format 是 -muxers 的一行
+type Format struct {
+	Name        string
+	Description string
+}
+
+// Capabilities 当前 ffmpeg 编译进来的编解码器、滤镜、封装格式和协议
+type Capabilities struct {
+	Encoders        map[string]Codec
+	Decoders        map[string]Codec
+	Filters         map[string]FilterInfo
+	Muxers          map[string]Format
+	InputProtocols  map[string]bool
+	OutputProtocols map[string]bool
+}
+
+func (c *Capabilities) HasEncoder(name string) bool { _, ok := c.Encoders[name]; return ok }
+func (c *Capabilities) HasDecoder(name string) bool { _, ok := c.Decoders[name]; return ok }
+func (c *Capabilities) HasFilter(name string) bool  { _, ok := c.Filters[name]; return ok }
+func (c *Capabilities) HasMuxer(name string) bool   { _, ok := c.Muxers[name]; return ok }
+
+// HasProtocol 输入或输出任一方向支持即可
+func (c *Capabilities) HasProtocol(name string) bool {
+	return c.InputProtocols[name] || c.OutputProtocols[name]
+}
+
+// EncodersOf 按类型列出编码器名（已排序），typ 为 "video" / "audio" / "subtitle"
+func (c *Capabilities) EncodersOf(typ string) []string {
+	var out []string
+	for name, codec := range c.Encoders {
+		if codec.Type == typ {
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Capabilities 执行 -encoders / -decoders / -filters / -muxers / -protocols 并解析，成功结果会缓存
+func (l *Locator) Capabilities(ctx context.Context) (*Capabilities, error) {
+	path, err := l.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	l.mu.Lock()
+	caps := l.caps
+	if l.path != path {
+		caps = nil
+	}
+	l.mu.Unlock()
+	if caps != nil {
+		return caps, nil
+	}
+
+	// 不持有 l.mu 执行：探测期间 Resolve（每次 Start 都会调用）不被阻塞；
+	// 每条命令受 CheckTimeout 限制，ffmpeg 卡住也不会一直等下去。并发的首次调用可能重复探测，结果相同
+	run := func(flag string) (string, error) {
+		cctx, cancel := context.WithTimeout(ctx, l.checkTimeout())
+		defer cancel()
+		var stdout, stderr bytes.Buffer
+		err := proc.Run(cctx, l.executor(), proc.Spec{
+			Path:   path,
+			Args:   []string{"-hide_banner", flag},
+			Stdout: &stdout,
+			Stderr: &stderr,
+		})
+		if err != nil {
+			if errors.Is(cctx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+				return "", fmt.Errorf("%s %s timed out after %s", l.Name, flag, l.checkTimeout())
+			}
+			return "", fmt.Errorf("%s %s: %w; stderr=%s", l.Name, flag, err, strings.TrimSpace(stderr.String()))
+		}
+		return stdout.String(), nil
+	}
+
+	caps = &Capabilities{}
+	out, err := run("-encoders")
+	if err != nil {
+		return nil, err
+	}
+	caps.Encoders = ParseCodecs(out)
+	if out, err = run("-decoders"); err != nil {
+		return nil, err
+	}
+	caps.Decoders = ParseCodecs(out)
+	if out, err = run("-filters"); err != nil {
+		return nil, err
+	}
+	caps.Filters = ParseFilters(out)
+	if out, err = run("-muxers"); err != nil {
+		return nil, err
+	}
+	caps.Muxers = ParseFormats(out)
+	if out, err = run("-protocols"); err != nil {
+		return nil, err
+	}
+	caps.InputProtocols, caps.OutputProtocols = ParseProtocols(out)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.path == path {
+		// 期间被 Reset 或换了可执行文件则不缓存
+		if l.caps == nil {
+			l.caps = caps
+		}
+		return l.caps, nil
+	}
+	return caps, nil
+}
+
+// afterSeparator 跳过图例，返回 " ------" / " ---" 分隔行之后的行；没有分隔行时返回全部
+func afterSeparator(s string) []string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		t := strings.TrimSpace(line)
+		if len(t) >= 2 && strings.Trim(t, "-") == "" {
+			return lines[i+1:]
+		}
+	}
+	return lines
+}
+
+var codecTypes = map[byte]string{'V': "video", 'A': "audio", 'S': "subtitle", 'D': "data", 'T': "attachment"}
+
+// ParseCodecs 解析 -encoders / -decoders 的输出
+func ParseCodecs(s string) map[string]Codec {
+	out := map[string]Codec{}
+	for _, line := range afterSeparator(s) {
+		f := strings.Fields(line)
+		if len(f) < 2 {
+			continue
+		}
+		c := Codec{Name: f[1], Flags: f[0], Type: codecTypes[f[0][0]]}
+		if len(f) > 2 {
+			c.Description = strings.Join(f[2:], " ")
+		}
+		out[c.Name] = c
+	}
+	return out
+}
+
+// ParseFilters 解析 -filters 的输出，例如 " TSC scale  V->V  Scale the input video size."
+func ParseFilters(s string) map[string]FilterInfo {
+	out := map[string]FilterInfo{}
+	for _, line := range strings.Split(s, "\n") {
+		f := strings.Fields(line)
+		if len(f) < 3 {
+			continue
+		}
+		in, outs, ok := strings.Cut(f[2], "->")
+		if !ok {
+			continue
+		}
+		fi := FilterInfo{Flags: f[0], Name: f[1], Inputs: in, Outputs: outs}
+		if len(f) > 3 {
+			fi.Description = strings.Join(f[3:], " ")
+		}
+		out[fi.Name] = fi
+	}
+	return out
+}
+
+// ParseFormats 解析 -muxers / -demuxers / -formats 的输出；"mov,mp4,m4a" 这类会拆成多个名字
+func ParseFormats(s string) map[string]Format {
+	out := map[string]Format{}
+	for _, line := range afterSeparator(s) {
+		f := strings.Fields(line)
+		if len(f) >= 3 && f[1] == "d" {
+			// 新版本的设备标记列：" E d alsa  ALSA audio output"
+			f = append([]string{f[0] + f[1]}, f[2:]...)
+		}
+		if len(f) < 2 {
+			continue
+		}
+		desc := strings.Join(f[2:], " ")
+		for _, name := range strings.Split(f[1], ",") {
+			if name != "" {
+				out[name] = Format{Name: name, Description: desc}
+			}
+		}
+	}
+	return out
+}
+
+// ParseProtocols 解析 -protocols 的 Input: / Output: 两段
+func ParseProtocols(s string) (input, output map[string]bool) {
+	input, output = map[string]bool{}, map[string]bool{}
+	var cur map[string]bool
+	for _, line := range strings.Split(s, "\n") {
+		t := strings.TrimSpace(line)
+		switch {
+		case t == "":
+		case strings.EqualFold(t, "Input:"):
+			cur = input
+		case strings.EqualFold(t, "Output:"):
+			cur = output
+		case cur != nil && !strings.HasSuffix(t, ":"):
+			cur[t] = true
+		}
+	}
+	return input, output
+}
@@ -0,0 +1,68 @@
+package locate_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LingByte/LingConvert/media/locate"
+	fftest "github.com/LingByte/LingConvert/media/testing"
+)
+
+func TestCapabilitiesDoesNotBlockResolve(t *testing.T) {
+	e := fftest.NewExecutor()
+	e.On("-encoders").Hang()
+	l := &locate.Locator{Name: "ffmpeg", Executor: e, CheckTimeout: 300 * time.Millisecond}
+	ctx := context.Background()
+	if _, err := l.Resolve(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := l.Capabilities(ctx)
+		done <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	start := time.Now()
+	if _, err := l.Resolve(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > 100*time.Millisecond {
+		t.Errorf("Resolve blocked for %s while capabilities were probed", d)
+	}
+
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "timed out") {
+			t.Errorf("Capabilities err = %v, want timeout", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Capabilities did not honour CheckTimeout")
+	}
+}
+
+func TestCapabilitiesCached(t *testing.T) {
+	e := fftest.NewExecutor()
+	e.On("-encoders").Stdout(" V....D libx264              libx264 H.264\n")
+	e.On("-hide_banner").Stdout("")
+	l := &locate.Locator{Name: "ffmpeg", Executor: e}
+	ctx := context.Background()
+
+	caps, err := l.Capabilities(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !caps.HasEncoder("libx264") {
+		t.Errorf("encoders = %v, want libx264", caps.Encoders)
+	}
+	n := len(e.Calls())
+	if _, err := l.Capabilities(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(e.Calls()) != n {
+		t.Errorf("second call ran ffmpeg again (%d -> %d calls)", n, len(e.Calls()))
+	}
+}
@@ -0,0 +1,268 @@
+// Package locate 查找 ffmpeg / ffprobe 可执行文件、检测版本和编译能力，供 ffmpeg.FFmpegTool 和 ffprobe.Tool 共用。
+//
+// 查找顺序：显式配置的路径 > 环境变量（LINGCONVERT_FFMPEG / LINGCONVERT_FFPROBE）> NextTo 同目录 > PATH。
+// 成功结果一直缓存；失败结果只缓存一段时间，之后按退避间隔重试。
+package locate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LingByte/LingConvert/media/proc"
+)
+
+const (
+	EnvFFmpeg  = "LINGCONVERT_FFMPEG"
+	EnvFFprobe = "LINGCONVERT_FFPROBE"
+)
+
+const (
+	DefaultCheckTimeout = 5 * time.Second
+	DefaultRetryMin     = time.Second
+	DefaultRetryMax     = 5 * time.Minute
+)
+
+// Locator 负责一个可执行文件的查找和检测，零值之外至少要设置 Name
+type Locator struct {
+	Name string // "ffmpeg" / "ffprobe"，也是在 PATH 里查找的名字
+	// Path 显式配置的路径；为空或等于 Name 时依次尝试环境变量、NextTo 同目录和 PATH
+	Path string
+	// Env 覆盖路径的环境变量，默认 "LINGCONVERT_" + 大写 Name
+	Env string
+	// NextTo 另一个可执行文件（名字或路径），优先用它同目录下的 Name，例如 ffmpeg 旁边的 ffprobe
+	NextTo string
+
+	Executor     proc.Executor // nil = proc.OS
+	CheckTimeout time.Duration // -version 检查超时，0 = DefaultCheckTimeout
+	RetryMin     time.Duration // 第一次失败后多久可以重试，之后翻倍，0 = DefaultRetryMin
+	RetryMax     time.Duration // 重试间隔上限，0 = DefaultRetryMax
+
+	mu      sync.Mutex
+	path    string
+	version string
+	info    *BuildInfo
+	err     error
+	retryAt time.Time
+	backoff time.Duration
+	caps    *Capabilities
+
+	gen      uint64        // Reset 时加一，用来丢弃过期的检测结果
+	inflight chan struct{} // 正在进行的查找，结束时关闭
+}
+
+// Resolve 返回可执行文件的绝对路径，首次调用时会执行 -version 确认可以运行
+func (l *Locator) Resolve(ctx context.Context) (string, error) {
+	st, err := l.check(ctx)
+	if err != nil {
+		return "", err
+	}
+	return st.path, nil
+}
+
+// Version 返回 -version 第一行里的版本号，无法识别时为 "unknown"
+func (l *Locator) Version(ctx context.Context) (string, error) {
+	st, err := l.check(ctx)
+	if err != nil {
+		return "", err
+	}
+	return st.version, nil
+}
+
+// BuildInfo 返回解析后的版本、编译配置和 libav* 库版本
+func (l *Locator) BuildInfo(ctx context.Context) (*BuildInfo, error) {
+	st, err := l.check(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return st.info, nil
+}
+
+// Reset 清掉缓存（包括成功结果），下次调用重新查找；正在进行的检测结果会被丢弃
+func (l *Locator) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.path, l.version, l.info, l.err, l.caps = "", "", nil, nil, nil
+	l.retryAt, l.backoff = time.Time{}, 0
+	l.gen++
+	l.inflight = nil
+}
+
+func (l *Locator) executor() proc.Executor {
+	if l.Executor != nil {
+		return l.Executor
+	}
+	return proc.OS
+}
+
+func (l *Locator) checkTimeout() time.Duration {
+	if l.CheckTimeout > 0 {
+		return l.CheckTimeout
+	}
+	return DefaultCheckTimeout
+}
+
+// located 检测结果的快照
+type located struct {
+	path    string
+	version string
+	info    *BuildInfo
+}
+
+// check 返回缓存的检测结果，没有时执行一次查找。
+// 查找（-version，最长 CheckTimeout）不持有 l.mu：同一时刻只有一个调用在查找，
+// 其余调用等它结束或自己的 ctx 取消；Reset 不会被阻塞
+func (l *Locator) check(ctx context.Context) (located, error) {
+	for {
+		l.mu.Lock()
+		if l.path != "" && l.err == nil {
+			st := located{l.path, l.version, l.info}
+			l.mu.Unlock()
+			return st, nil
+		}
+		if l.err != nil && time.Now().Before(l.retryAt) {
+			err := l.err
+			l.mu.Unlock()
+			return located{}, err
+		}
+		if wait := l.inflight; wait != nil {
+			l.mu.Unlock()
+			select {
+			case <-wait:
+				continue
+			case <-ctx.Done():
+				return located{}, ctx.Err()
+			}
+		}
+		done := make(chan struct{})
+		l.inflight = done
+		gen := l.gen
+		l.mu.Unlock()
+
+		path, info, err := l.find(ctx)
+
+		l.mu.Lock()
+		if l.inflight == done {
+			l.inflight = nil
+		}
+		close(done)
+		st := located{path: path, info: info}
+		if err == nil {
+			st.version = info.Version.Raw
+			if st.version == "" {
+				st.version = "unknown"
+			}
+		}
+		if gen != l.gen {
+			// 期间被 Reset：结果只给本次调用，不缓存
+			l.mu.Unlock()
+			return st, err
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				// 调用方自己取消的不算检测失败
+				l.backoff = nextBackoff(l.backoff, l.RetryMin, l.RetryMax)
+				l.retryAt = time.Now().Add(l.backoff)
+				l.err = err
+			}
+			l.mu.Unlock()
+			return located{}, err
+		}
+		l.path, l.version, l.info, l.err = st.path, st.version, st.info, nil
+		l.backoff, l.retryAt = 0, time.Time{}
+		l.mu.Unlock()
+		return st, nil
+	}
+}
+
+func nextBackoff(cur, min, max time.Duration) time.Duration {
+	if min <= 0 {
+		min = DefaultRetryMin
+	}
+	if max <= 0 {
+		max = DefaultRetryMax
+	}
+	if cur <= 0 {
+		return min
+	}
+	if cur *= 2; cur > max {
+		cur = max
+	}
+	return cur
+}
+
+func (l *Locator) find(ctx context.Context) (string, *BuildInfo, error) {
+	exe := l.executor()
+	name := l.Name
+	path, source := l.Path, "configured"
+	if path == "" || path == name {
+		path, source = "", ""
+		env := l.Env
+		if env == "" {
+			env = "LINGCONVERT_" + strings.ToUpper(name)
+		}
+		if v := os.Getenv(env); v != "" {
+			path, source = v, env
+		} else if sib := l.sibling(); sib != "" {
+			if _, err := exe.LookPath(sib); err == nil {
+				path, source = sib, "next to "+l.NextTo
+			}
+		}
+		if path == "" {
+			path, source = name, "PATH"
+		}
+	}
+
+	resolved, err := exe.LookPath(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s not found (%s=%q): %w", name, source, path, err)
+	}
+
+	cctx, cancel := context.WithTimeout(ctx, l.checkTimeout())
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	runErr := proc.Run(cctx, exe, proc.Spec{Path: resolved, Args: []string{"-version"}, Stdout: &stdout, Stderr: &stderr})
+	if runErr != nil {
+		if errors.Is(cctx.Err(), context.DeadlineExceeded) {
+			return "", nil, fmt.Errorf("%s check timed out (path=%q)", name, resolved)
+		}
+		return "", nil, fmt.Errorf("%s exists but cannot run (path=%q): %w; stderr=%s",
+			name, resolved, runErr, strings.TrimSpace(stderr.String()))
+	}
+	return resolved, ParseBuildInfo(stdout.String()), nil
+}
+
+// sibling NextTo 同目录下的 Name；NextTo 只是名字时先在 PATH 里找到它
+func (l *Locator) sibling() string {
+	next := l.NextTo
+	if next == "" {
+		return ""
+	}
+	if !strings.ContainsAny(next, `/\`) {
+		p, err := l.executor().LookPath(next)
+		if err != nil {
+			return ""
+		}
+		next = p
+	}
+	return filepath.Join(filepath.Dir(next), l.Name+filepath.Ext(next))
+}
+
+// ParseVersionLine 从 "ffmpeg version 6.1.1-static ..." 里取出 "6.1.1-static"
+func ParseVersionLine(s string) string {
+	first, _, _ := strings.Cut(s, "\n")
+	parts := strings.Fields(first)
+	for i := 0; i < len(parts)-1; i++ {
+		if strings.ToLower(parts[i]) == "version" {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,106 @@
+package locate_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/LingByte/LingConvert/media/locate"
+	fftest "github.com/LingByte/LingConvert/media/testing"
+)
+
+func versionCalls(e *fftest.Executor) int {
+	n := 0
+	for _, c := range e.Calls() {
+		if strings.Join(c.Args, " ") == "-version" {
+			n++
+		}
+	}
+	return n
+}
+
+func TestCheckDoesNotHoldLock(t *testing.T) {
+	e := fftest.NewExecutor()
+	e.On("-version").Hang()
+	l := &locate.Locator{Name: "ffmpeg", Executor: e, CheckTimeout: 500 * time.Millisecond}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := l.Resolve(context.Background())
+		done <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// 其他调用等正在进行的检测，按自己的 ctx 返回
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := l.Version(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Version err = %v, want deadline exceeded", err)
+	}
+	if d := time.Since(start); d > 300*time.Millisecond {
+		t.Errorf("Version ignored its ctx for %s", d)
+	}
+
+	start = time.Now()
+	l.Reset()
+	if d := time.Since(start); d > 100*time.Millisecond {
+		t.Errorf("Reset blocked for %s while -version was running", d)
+	}
+
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "timed out") {
+			t.Errorf("Resolve err = %v, want timeout", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Resolve did not honour CheckTimeout")
+	}
+}
+
+func TestCheckSingleFlight(t *testing.T) {
+	e := fftest.NewExecutor()
+	e.On("-version").Stdout("ffmpeg version 6.1.1 Copyright (c) 2000-2023\n")
+	l := &locate.Locator{Name: "ffmpeg", Executor: e}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := l.Version(context.Background()); err != nil || v != "6.1.1" {
+				t.Errorf("Version = %q, %v", v, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := versionCalls(e); n != 1 {
+		t.Errorf("-version ran %d times, want 1", n)
+	}
+
+	l.Reset()
+	if _, err := l.Resolve(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := versionCalls(e); n != 2 {
+		t.Errorf("-version ran %d times after Reset, want 2", n)
+	}
+}
+
+func TestCheckFailureBackoff(t *testing.T) {
+	e := fftest.NewExecutor()
+	e.On("-version").Exit(1)
+	l := &locate.Locator{Name: "ffmpeg", Executor: e, RetryMin: time.Hour}
+
+	for i := 0; i < 3; i++ {
+		if _, err := l.Resolve(context.Background()); err == nil || !strings.Contains(err.Error(), "cannot run") {
+			t.Fatalf("Resolve err = %v, want cannot run", err)
+		}
+	}
+	if n := versionCalls(e); n != 1 {
+		t.Errorf("-version ran %d times within the retry window, want 1", n)
+	}
+}
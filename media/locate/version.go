@@ -0,0 +1,214 @@
+package locate
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// VersionKind 区分正式版本号和 git 快照
+type VersionKind int
+
+const (
+	VersionUnknown  VersionKind = iota
+	VersionRelease              // "6.1.1"、"n6.1"、"4.4.2-0ubuntu0.22.04.1"、"6.0-full_build-www.gyan.dev"
+	VersionSnapshot             // "N-112345-gabcdef0"、"2024-01-01-git-abcdef-full_build"、"git-2023-05-01-abcdef"
+)
+
+// Version 解析后的 ffmpeg 版本号
+type Version struct {
+	Raw    string
+	Kind   VersionKind
+	Major  int
+	Minor  int
+	Patch  int
+	Suffix string // 发行版/构建后缀，例如 "-static"、"-0ubuntu0.22.04.1"
+
+	// 仅快照：N-112345 的 112345 和 git 提交
+	Revision int
+	Commit   string
+}
+
+var (
+	releaseRe  = regexp.MustCompile(`^n?(\d+)\.(\d+)(?:\.(\d+))?(.*)$`)
+	snapshotRe = regexp.MustCompile(`^N-(\d+)-g([0-9a-f]+)`)
+	gitDateRe  = regexp.MustCompile(`git-([0-9a-f]{7,})|^git-\d{4}-\d{2}-\d{2}-([0-9a-f]+)`)
+)
+
+// ParseVersion 解析 -version 第一行里的版本号（见 ParseVersionLine），无法识别时 Kind 为 VersionUnknown
+func ParseVersion(s string) Version {
+	v := Version{Raw: s}
+	if m := releaseRe.FindStringSubmatch(s); m != nil {
+		v.Kind = VersionRelease
+		v.Major, _ = strconv.Atoi(m[1])
+		v.Minor, _ = strconv.Atoi(m[2])
+		if m[3] != "" {
+			v.Patch, _ = strconv.Atoi(m[3])
+		}
+		v.Suffix = m[4]
+		return v
+	}
+	if m := snapshotRe.FindStringSubmatch(s); m != nil {
+		v.Kind = VersionSnapshot
+		v.Revision, _ = strconv.Atoi(m[1])
+		v.Commit = m[2]
+		return v
+	}
+	if m := gitDateRe.FindStringSubmatch(s); m != nil {
+		v.Kind = VersionSnapshot
+		v.Commit = m[1] + m[2]
+	}
+	return v
+}
+
+func (v Version) String() string {
+	if v.Raw != "" {
+		return v.Raw
+	}
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Compare 只比较 major.minor.patch（-1 / 0 / 1），后缀不参与
+func (v Version) Compare(o Version) int {
+	for _, d := range [3]int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d < 0 {
+			return -1
+		}
+		if d > 0 {
+			return 1
+		}
+	}
+	return 0
+}
+
+// LibVersion libavcodec 等库的版本，例如 60.31.102
+type LibVersion struct {
+	Major, Minor, Micro int
+}
+
+// ParseLibVersion 解析 "60.31.102" / "60. 31.102"
+func ParseLibVersion(s string) (LibVersion, error) {
+	parts := strings.Split(strings.ReplaceAll(s, " ", ""), ".")
+	var n [3]int
+	if len(parts) == 0 || len(parts) > 3 {
+		return LibVersion{}, fmt.Errorf("invalid library version %q", s)
+	}
+	for i, p := range parts {
+		x, err := strconv.Atoi(p)
+		if err != nil {
+			return LibVersion{}, fmt.Errorf("invalid library version %q", s)
+		}
+		n[i] = x
+	}
+	return LibVersion{n[0], n[1], n[2]}, nil
+}
+
+func (l LibVersion) String() string { return fmt.Sprintf("%d.%d.%d", l.Major, l.Minor, l.Micro) }
+
+func (l LibVersion) Compare(o LibVersion) int {
+	for _, d := range [3]int{l.Major - o.Major, l.Minor - o.Minor, l.Micro - o.Micro} {
+		if d < 0 {
+			return -1
+		}
+		if d > 0 {
+			return 1
+		}
+	}
+	return 0
+}
+
+// BuildInfo 从 -version 输出解析出的版本、配置和各库版本
+type BuildInfo struct {
+	Version       Version
+	Configuration []string              // "--enable-gpl" 等
+	Libraries     map[string]LibVersion // "libavcodec" => 60.31.102（运行时加载的版本）
+}
+
+var libLineRe = regexp.MustCompile(`^\s*(lib\w+)\s+([\d. ]+?)\s*(?:/\s*([\d. ]+?))?\s*$`)
+
+// ParseBuildInfo 解析完整的 -version 输出
+func ParseBuildInfo(out string) *BuildInfo {
+	info := &BuildInfo{
+		Version:   ParseVersion(ParseVersionLine(out)),
+		Libraries: map[string]LibVersion{},
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if rest, ok := strings.CutPrefix(strings.TrimSpace(line), "configuration:"); ok {
+			info.Configuration = strings.Fields(rest)
+			continue
+		}
+		m := libLineRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		s := m[2]
+		if m[3] != "" {
+			s = m[3]
+		}
+		if lv, err := ParseLibVersion(s); err == nil {
+			info.Libraries[m[1]] = lv
+		}
+	}
+	return info
+}
+
+// HasLibrary libav* 库（libavfilter 等）存在，或者编译时 --enable-<name>（libx264、libvmaf 等）
+func (b *BuildInfo) HasLibrary(name string) bool {
+	if _, ok := b.Libraries[name]; ok {
+		return true
+	}
+	for _, c := range b.Configuration {
+		if c == "--enable-"+name {
+			return true
+		}
+	}
+	return false
+}
+
+// releaseAvcodec 各正式版本自带的 libavcodec 版本，用来判断快照版相当于哪个正式版
+var releaseAvcodec = []struct {
+	major, minor int
+	lib          LibVersion
+}{
+	{4, 0, LibVersion{58, 18, 100}},
+	{4, 1, LibVersion{58, 35, 100}},
+	{4, 2, LibVersion{58, 54, 100}},
+	{4, 3, LibVersion{58, 91, 100}},
+	{4, 4, LibVersion{58, 134, 100}},
+	{5, 0, LibVersion{59, 18, 100}},
+	{5, 1, LibVersion{59, 37, 100}},
+	{6, 0, LibVersion{60, 3, 100}},
+	{6, 1, LibVersion{60, 31, 102}},
+	{7, 0, LibVersion{61, 3, 100}},
+	{7, 1, LibVersion{61, 19, 100}},
+	{8, 0, LibVersion{62, 11, 100}},
+}
+
+// AtLeast 判断是否不低于 min。快照版没有版本号，按 libavcodec 版本和正式版对照；
+// 无法判断（版本号不认识、缺少库信息）时返回 true，不拦截
+func (b *BuildInfo) AtLeast(min Version) bool {
+	switch b.Version.Kind {
+	case VersionRelease:
+		return b.Version.Compare(min) >= 0
+	case VersionSnapshot:
+		have, ok := b.Libraries["libavcodec"]
+		if !ok {
+			return true
+		}
+		var need *LibVersion
+		for i := range releaseAvcodec {
+			r := &releaseAvcodec[i]
+			if r.major > min.Major || (r.major == min.Major && r.minor >= min.Minor) {
+				need = &r.lib
+				break
+			}
+		}
+		if need == nil {
+			// 比对照表里最新的版本还新：只能看 major 是否已经超过
+			return have.Major > releaseAvcodec[len(releaseAvcodec)-1].lib.Major
+		}
+		return have.Compare(*need) >= 0
+	}
+	return true
+}
@@ -0,0 +1,433 @@
+// Package preset 从 YAML / JSON 加载具名的输出配置（preset），
+// 带类型化参数、默认值和校验，按名字列出、描述并实例化成 ffmpeg.FFmpegCommand。
+//
+// 文件格式（JSON 同结构）：
+//
+//	presets:
+//	  - name: mp4-h264
+//	    description: H.264 + AAC MP4
+//	    extension: .mp4
+//	    requires: [{library: libx264, reason: H.264 encoding}]
+//	    params:
+//	      - {name: crf, type: int, default: 23, min: 0, max: 51}
+//	      - {name: tune, type: string, enum: [film, animation]}
+//	    global: [-hide_banner, -v, error]
+//	    output: [-c:v, libx264, -crf, "${crf}", -tune, "${tune}"]
+//
+// 参数用 ${name} 引用；没有值的可选参数展开为空时，该参数连同前面的选项名一起省略（上例没给 tune 就没有 -tune）
+package preset
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LingByte/LingConvert/media/ffmpeg"
+)
+
+var (
+	ErrNotFound     = errors.New("preset: not found")
+	ErrDuplicate    = errors.New("preset: duplicate name")
+	ErrInvalid      = errors.New("preset: invalid definition")
+	ErrInvalidParam = errors.New("preset: invalid parameter")
+)
+
+type ParamType string
+
+const (
+	TypeString   ParamType = "string"
+	TypeInt      ParamType = "int"
+	TypeFloat    ParamType = "float"
+	TypeBool     ParamType = "bool"     // 渲染成 1 / 0
+	TypeDuration ParamType = "duration" // "1m30s"、"90" 或 "01:30"，渲染成秒
+)
+
+// Param 一个参数的声明
+type Param struct {
+	Name        string    `yaml:"name" json:"name"`
+	Type        ParamType `yaml:"type" json:"type"` // 默认 string
+	Description string    `yaml:"description" json:"description,omitempty"`
+	Default     any       `yaml:"default" json:"default,omitempty"`
+	Required    bool      `yaml:"required" json:"required,omitempty"`
+	Enum        []string  `yaml:"enum" json:"enum,omitempty"`
+	Min         *float64  `yaml:"min" json:"min,omitempty"`
+	Max         *float64  `yaml:"max" json:"max,omitempty"`
+}
+
+// Requirement 对应 ffmpeg.Requirement
+type Requirement struct {
+	MinVersion string `yaml:"min_version" json:"min_version,omitempty"`
+	Library    string `yaml:"library" json:"library,omitempty"`
+	MinLibrary string `yaml:"min_library" json:"min_library,omitempty"`
+	Reason     string `yaml:"reason" json:"reason,omitempty"`
+}
+
+// Preset 一个具名输出配置
+type Preset struct {
+	Name        string        `yaml:"name" json:"name"`
+	Description string        `yaml:"description" json:"description,omitempty"`
+	Extension   string        `yaml:"extension" json:"extension,omitempty"` // 建议的输出扩展名，例如 ".mp4"
+	Params      []Param       `yaml:"params" json:"params,omitempty"`
+	Requires    []Requirement `yaml:"requires" json:"requires,omitempty"`
+
+	Global      []string `yaml:"global" json:"global,omitempty"` // 全局选项
+	Input       []string `yaml:"input" json:"input,omitempty"`   // 写在 -i 之前的输入选项
+	Output      []string `yaml:"output" json:"output,omitempty"` // 写在输出路径之前的选项
+	VideoFilter string   `yaml:"vf" json:"vf,omitempty"`         // 参数值会按 filtergraph 规则转义
+	AudioFilter string   `yaml:"af" json:"af,omitempty"`
+}
+
+// Param 按名字找参数声明
+func (p *Preset) Param(name string) (Param, bool) {
+	for _, pa := range p.Params {
+		if pa.Name == name {
+			return pa, true
+		}
+	}
+	return Param{}, false
+}
+
+// check 检查定义本身：名字、类型、默认值、模板里引用的参数都存在
+func (p *Preset) check() error {
+	var errs []error
+	if strings.TrimSpace(p.Name) == "" {
+		errs = append(errs, errors.New("name is empty"))
+	}
+	seen := map[string]bool{}
+	for i := range p.Params {
+		pa := p.Params[i]
+		switch {
+		case pa.Name == "":
+			errs = append(errs, fmt.Errorf("param #%d has no name", i))
+			continue
+		case seen[pa.Name]:
+			errs = append(errs, fmt.Errorf("param %q declared twice", pa.Name))
+		}
+		seen[pa.Name] = true
+		switch pa.typ() {
+		case TypeString, TypeInt, TypeFloat, TypeBool, TypeDuration:
+		default:
+			errs = append(errs, fmt.Errorf("param %q: unknown type %q", pa.Name, pa.Type))
+			continue
+		}
+		if pa.Default != nil {
+			if _, err := pa.value(pa.Default); err != nil {
+				errs = append(errs, fmt.Errorf("param %q: default: %w", pa.Name, err))
+			}
+		}
+	}
+	for _, s := range p.templates() {
+		for _, ref := range refs(s) {
+			if !seen[ref] {
+				errs = append(errs, fmt.Errorf("%q references undeclared param %q", s, ref))
+			}
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("%w %q: %w", ErrInvalid, p.Name, err)
+	}
+	return nil
+}
+
+func (p *Preset) templates() []string {
+	out := make([]string, 0, len(p.Global)+len(p.Input)+len(p.Output)+2)
+	out = append(out, p.Global...)
+	out = append(out, p.Input...)
+	out = append(out, p.Output...)
+	return append(out, p.VideoFilter, p.AudioFilter)
+}
+
+// Resolve 合并默认值和覆盖值，校验后返回渲染好的参数字符串；所有问题一次性返回
+func (p *Preset) Resolve(overrides map[string]any) (map[string]string, error) {
+	var errs []error
+	for name := range overrides {
+		if _, ok := p.Param(name); !ok {
+			errs = append(errs, fmt.Errorf("%w: %s: unknown param %q", ErrInvalidParam, p.Name, name))
+		}
+	}
+	out := make(map[string]string, len(p.Params))
+	for _, pa := range p.Params {
+		raw, ok := overrides[pa.Name]
+		if !ok || raw == nil || raw == "" {
+			raw = pa.Default
+		}
+		if raw == nil {
+			if pa.Required {
+				errs = append(errs, fmt.Errorf("%w: %s: %q is required", ErrInvalidParam, p.Name, pa.Name))
+			}
+			out[pa.Name] = ""
+			continue
+		}
+		v, err := pa.value(raw)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w: %s: %q: %w", ErrInvalidParam, p.Name, pa.Name, err))
+			continue
+		}
+		out[pa.Name] = v
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// typ 未写类型的参数按 string 处理
+func (pa Param) typ() ParamType {
+	if pa.Type == "" {
+		return TypeString
+	}
+	return pa.Type
+}
+
+// normalize 补上省略的类型，加载文件时调用
+func (p *Preset) normalize() {
+	for i := range p.Params {
+		p.Params[i].Type = p.Params[i].typ()
+	}
+}
+
+// value 按类型校验并渲染成命令行里的字符串；raw 可以是 YAML/JSON 解出来的值，也可以是表单里的字符串
+func (pa Param) value(raw any) (string, error) {
+	var (
+		s   string
+		num float64
+		isN bool
+	)
+	switch pa.typ() {
+	case TypeInt:
+		n, err := toFloat(raw)
+		if err != nil || n != math.Trunc(n) {
+			return "", fmt.Errorf("want an integer, got %v", raw)
+		}
+		s, num, isN = strconv.FormatInt(int64(n), 10), n, true
+	case TypeFloat:
+		n, err := toFloat(raw)
+		if err != nil {
+			return "", fmt.Errorf("want a number, got %v", raw)
+		}
+		s, num, isN = strconv.FormatFloat(n, 'f', -1, 64), n, true
+	case TypeDuration:
+		d, err := toDuration(raw)
+		if err != nil {
+			return "", err
+		}
+		num, isN = d.Seconds(), true
+		s = strconv.FormatFloat(num, 'f', -1, 64)
+	case TypeBool:
+		b, err := toBool(raw)
+		if err != nil {
+			return "", err
+		}
+		s = "0"
+		if b {
+			s = "1"
+		}
+	default:
+		s = fmt.Sprint(raw)
+	}
+	if len(pa.Enum) > 0 && !contains(pa.Enum, s) {
+		return "", fmt.Errorf("%q is not one of %s", s, strings.Join(pa.Enum, ", "))
+	}
+	if isN && pa.Min != nil && num < *pa.Min {
+		return "", fmt.Errorf("%s is below the minimum %v", s, *pa.Min)
+	}
+	if isN && pa.Max != nil && num > *pa.Max {
+		return "", fmt.Errorf("%s is above the maximum %v", s, *pa.Max)
+	}
+	return s, nil
+}
+
+func toFloat(raw any) (float64, error) {
+	switch v := raw.(type) {
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(v), 64)
+	}
+	return 0, fmt.Errorf("unsupported value %T", raw)
+}
+
+func toBool(raw any) (bool, error) {
+	switch v := raw.(type) {
+	case bool:
+		return v, nil
+	case string:
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "1", "true", "yes", "on":
+			return true, nil
+		case "0", "false", "no", "off":
+			return false, nil
+		}
+	case int, int64, uint64, float64:
+		n, _ := toFloat(v)
+		return n != 0, nil
+	}
+	return false, fmt.Errorf("want a boolean, got %v", raw)
+}
+
+// toDuration 接受 time.Duration、Go 时长字符串、秒数或 [HH:]MM:SS[.ms]
+func toDuration(raw any) (time.Duration, error) {
+	switch v := raw.(type) {
+	case time.Duration:
+		return v, nil
+	case string:
+		v = strings.TrimSpace(v)
+		if d, err := time.ParseDuration(v); err == nil {
+			return d, nil
+		}
+		if strings.Contains(v, ":") {
+			var secs float64
+			for _, part := range strings.Split(v, ":") {
+				n, err := strconv.ParseFloat(part, 64)
+				if err != nil {
+					return 0, fmt.Errorf("want a duration, got %q", v)
+				}
+				secs = secs*60 + n
+			}
+			return time.Duration(secs * float64(time.Second)), nil
+		}
+	}
+	n, err := toFloat(raw)
+	if err != nil {
+		return 0, fmt.Errorf("want a duration, got %v", raw)
+	}
+	return time.Duration(n * float64(time.Second)), nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Build 用参数实例化命令
+func (p *Preset) Build(input, output string, overrides map[string]any) (*ffmpeg.FFmpegCommand, error) {
+	vals, err := p.Resolve(overrides)
+	if err != nil {
+		return nil, err
+	}
+	cmd := ffmpeg.NewFFmpegCommand().GlobalArgs(expandArgs(p.Global, vals)...)
+	for _, r := range p.Requires {
+		cmd.Require(ffmpeg.Requirement(r))
+	}
+	cmd.AddInput(input).Option(expandArgs(p.Input, vals)...)
+	o := cmd.AddOutput(output).Option(expandArgs(p.Output, vals)...)
+	if vf := expandFilter(p.VideoFilter, vals); vf != "" {
+		o.VideoFilter(ffmpeg.RawFilter(vf))
+	}
+	if af := expandFilter(p.AudioFilter, vals); af != "" {
+		o.AudioFilter(ffmpeg.RawFilter(af))
+	}
+	return cmd, nil
+}
+
+// expandArgs 替换 ${name}；展开为空的参数省略，前面紧挨着的选项名一起省略
+func expandArgs(tmpl []string, vals map[string]string) []string {
+	out := make([]string, 0, len(tmpl))
+	for _, a := range tmpl {
+		s := expand(a, vals, nil)
+		if s == "" && len(refs(a)) > 0 {
+			if n := len(out); n > 0 && strings.HasPrefix(out[n-1], "-") {
+				out = out[:n-1]
+			}
+			continue
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+func expandFilter(tmpl string, vals map[string]string) string {
+	return expand(tmpl, vals, ffmpeg.EscapeFilterGraphValue)
+}
+
+func expand(s string, vals map[string]string, escape func(string) string) string {
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			break
+		}
+		j := strings.IndexByte(s[i:], '}')
+		if j < 0 {
+			break
+		}
+		v := vals[s[i+2:i+j]]
+		if escape != nil {
+			v = escape(v)
+		}
+		b.WriteString(s[:i])
+		b.WriteString(v)
+		s = s[i+j+1:]
+	}
+	b.WriteString(s)
+	return b.String()
+}
+
+func refs(s string) []string {
+	var out []string
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			return out
+		}
+		j := strings.IndexByte(s[i:], '}')
+		if j < 0 {
+			return out
+		}
+		out = append(out, s[i+2:i+j])
+		s = s[i+j+1:]
+	}
+}
+
+// Describe 人可读的说明，列出参数、类型、默认值和取值范围
+func (p *Preset) Describe() string {
+	var b strings.Builder
+	b.WriteString(p.Name)
+	if p.Description != "" {
+		b.WriteString(" - " + p.Description)
+	}
+	b.WriteByte('\n')
+	params := append([]Param(nil), p.Params...)
+	sort.SliceStable(params, func(i, k int) bool { return params[i].Required && !params[k].Required })
+	for _, pa := range params {
+		fmt.Fprintf(&b, "  %s (%s)", pa.Name, pa.typ())
+		if pa.Required {
+			b.WriteString(" required")
+		}
+		if pa.Default != nil {
+			fmt.Fprintf(&b, " default=%v", pa.Default)
+		}
+		if len(pa.Enum) > 0 {
+			fmt.Fprintf(&b, " one of [%s]", strings.Join(pa.Enum, ", "))
+		}
+		if pa.Min != nil || pa.Max != nil {
+			b.WriteString(" range=" + bound(pa.Min) + ".." + bound(pa.Max))
+		}
+		if pa.Description != "" {
+			b.WriteString(": " + pa.Description)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func bound(f *float64) string {
+	if f == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*f, 'f', -1, 64)
+}
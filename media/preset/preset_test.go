@@ -0,0 +1,176 @@
+package preset
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func ptr(f float64) *float64 { return &f }
+
+func TestResolve(t *testing.T) {
+	p := &Preset{
+		Name: "test",
+		Params: []Param{
+			{Name: "crf", Type: TypeInt, Default: 23, Min: ptr(0), Max: ptr(51)},
+			{Name: "gain", Type: TypeFloat},
+			{Name: "tune", Enum: []string{"film", "animation"}},
+			{Name: "loop", Type: TypeBool, Default: false},
+			{Name: "at", Type: TypeDuration, Default: "0"},
+			{Name: "title", Required: true},
+		},
+	}
+	tests := []struct {
+		name      string
+		overrides map[string]any
+		want      map[string]string
+		errs      []string
+	}{
+		{
+			name:      "defaults",
+			overrides: map[string]any{"title": "x"},
+			want:      map[string]string{"crf": "23", "gain": "", "tune": "", "loop": "0", "at": "0", "title": "x"},
+		},
+		{
+			name: "typed and string values",
+			overrides: map[string]any{"crf": "18", "gain": 1.5, "tune": "film", "loop": "yes",
+				"at": "01:30.5", "title": "x"},
+			want: map[string]string{"crf": "18", "gain": "1.5", "tune": "film", "loop": "1", "at": "90.5", "title": "x"},
+		},
+		{
+			name:      "duration forms",
+			overrides: map[string]any{"at": time.Minute, "title": "x"},
+			want:      map[string]string{"crf": "23", "gain": "", "tune": "", "loop": "0", "at": "60", "title": "x"},
+		},
+		{
+			name:      "go duration string",
+			overrides: map[string]any{"at": "1m30s", "title": "x"},
+			want:      map[string]string{"crf": "23", "gain": "", "tune": "", "loop": "0", "at": "90", "title": "x"},
+		},
+		{
+			name:      "missing required",
+			overrides: map[string]any{},
+			errs:      []string{`"title" is required`},
+		},
+		{
+			name:      "unknown param",
+			overrides: map[string]any{"title": "x", "bogus": 1},
+			errs:      []string{`unknown param "bogus"`},
+		},
+		{
+			name: "type errors reported together",
+			overrides: map[string]any{"title": "x", "crf": "abc", "gain": "fast", "loop": "maybe",
+				"at": "soon", "tune": "grain"},
+			errs: []string{"want an integer", "want a number", "want a boolean", "want a duration", "not one of film, animation"},
+		},
+		{
+			name:      "fractional int",
+			overrides: map[string]any{"title": "x", "crf": 18.5},
+			errs:      []string{"want an integer"},
+		},
+		{
+			name:      "range",
+			overrides: map[string]any{"title": "x", "crf": 52},
+			errs:      []string{"above the maximum 51"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.Resolve(tt.overrides)
+			if len(tt.errs) > 0 {
+				if !errors.Is(err, ErrInvalidParam) {
+					t.Fatalf("err = %v, want ErrInvalidParam", err)
+				}
+				for _, e := range tt.errs {
+					if !strings.Contains(err.Error(), e) {
+						t.Errorf("err = %v, missing %q", err, e)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Resolve = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name string
+		p    Preset
+		err  string
+	}{
+		{"ok, type omitted", Preset{Name: "a", Params: []Param{{Name: "x"}}, Output: []string{"-b:a", "${x}"}}, ""},
+		{"no name", Preset{}, "name is empty"},
+		{"unknown type", Preset{Name: "a", Params: []Param{{Name: "x", Type: "number"}}}, `unknown type "number"`},
+		{"bad default", Preset{Name: "a", Params: []Param{{Name: "x", Type: TypeInt, Default: "high"}}}, "default: want an integer"},
+		{"duplicate param", Preset{Name: "a", Params: []Param{{Name: "x"}, {Name: "x"}}}, "declared twice"},
+		{"undeclared ref", Preset{Name: "a", VideoFilter: "scale=${w}:-2"}, `undeclared param "w"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := append([]Param(nil), tt.p.Params...)
+			err := tt.p.check()
+			if tt.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+			} else if !errors.Is(err, ErrInvalid) || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("check = %v, want %q", err, tt.err)
+			}
+			if !reflect.DeepEqual(tt.p.Params, before) {
+				t.Errorf("check modified params: %+v", tt.p.Params)
+			}
+		})
+	}
+}
+
+func TestBuild(t *testing.T) {
+	p := &Preset{
+		Name: "test",
+		Params: []Param{
+			{Name: "crf", Type: TypeInt, Default: 23},
+			{Name: "tune"},
+			{Name: "text", Default: "a,b: it's"},
+			{Name: "at", Type: TypeDuration},
+		},
+		Global:      []string{"-hide_banner"},
+		Input:       []string{"-ss", "${at}"},
+		Output:      []string{"-c:v", "libx264", "-crf", "${crf}", "-tune", "${tune}"},
+		VideoFilter: "drawtext=text=${text}",
+	}
+	cmd, err := p.Build("in.mp4", "out.mp4", map[string]any{"crf": 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 没给 tune / at：连同前面的选项名一起省略；滤镜里的值按 filtergraph 规则转义
+	want := []string{"-y", "-hide_banner", "-i", "in.mp4", "-c:v", "libx264", "-crf", "20",
+		"-vf", `drawtext=text=a\,b\\: it\\\'s`, "out.mp4"}
+	if got := cmd.Args(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Args = %q, want %q", got, want)
+	}
+}
+
+func TestExpandArgs(t *testing.T) {
+	vals := map[string]string{"a": "1", "empty": ""}
+	tests := []struct {
+		in, want []string
+	}{
+		{[]string{"-x", "${a}"}, []string{"-x", "1"}},
+		{[]string{"-x", "${empty}", "-y", "2"}, []string{"-y", "2"}},
+		{[]string{"pre-${a}-post"}, []string{"pre-1-post"}},
+		{[]string{"${empty}"}, []string{}},
+		{[]string{"-x", "${unclosed"}, []string{"-x", "${unclosed"}},
+	}
+	for _, tt := range tests {
+		if got := expandArgs(tt.in, vals); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("expandArgs(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
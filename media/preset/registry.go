@@ -0,0 +1,188 @@
+package preset
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/goccy/go-yaml"
+
+	"github.com/LingByte/LingConvert/media/ffmpeg"
+)
+
+// Registry 按名字管理 preset，并发安全
+type Registry struct {
+	mu      sync.RWMutex
+	presets map[string]*Preset
+}
+
+func NewRegistry() *Registry {
+	return &Registry{presets: map[string]*Preset{}}
+}
+
+// Register 校验定义并注册；同名已存在返回 ErrDuplicate
+func (r *Registry) Register(p *Preset) error {
+	if err := p.check(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.presets[p.Name]; ok {
+		return fmt.Errorf("%w: %s", ErrDuplicate, p.Name)
+	}
+	r.presets[p.Name] = p
+	return nil
+}
+
+// file 文件的顶层结构：presets 列表，或者整个文件就是一个 preset
+type file struct {
+	Presets []*Preset `yaml:"presets" json:"presets"`
+}
+
+// Parse 解析 YAML 或 JSON（JSON 是 YAML 的子集）；未知字段报错，避免拼错的键被悄悄忽略
+func Parse(data []byte) ([]*Preset, error) {
+	var top map[string]any
+	if err := yaml.Unmarshal(data, &top); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+	if _, ok := top["presets"]; ok {
+		var f file
+		if err := yaml.UnmarshalWithOptions(data, &f, yaml.Strict()); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalid, err)
+		}
+		for _, p := range f.Presets {
+			if p != nil {
+				p.normalize()
+			}
+		}
+		return f.Presets, nil
+	}
+	var p Preset
+	if err := yaml.UnmarshalWithOptions(data, &p, yaml.Strict()); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+	p.normalize()
+	return []*Preset{&p}, nil
+}
+
+// Load 解析并注册 data 里的全部 preset；任何一个无效则都不注册
+func (r *Registry) Load(data []byte) error {
+	ps, err := Parse(data)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for i, p := range ps {
+		if p == nil {
+			errs = append(errs, fmt.Errorf("%w: preset #%d is empty", ErrInvalid, i))
+			continue
+		}
+		if err := p.check(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	seen := map[string]bool{}
+	for _, p := range ps {
+		if _, ok := r.presets[p.Name]; ok || seen[p.Name] {
+			return fmt.Errorf("%w: %s", ErrDuplicate, p.Name)
+		}
+		seen[p.Name] = true
+	}
+	for _, p := range ps {
+		r.presets[p.Name] = p
+	}
+	return nil
+}
+
+func (r *Registry) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := r.Load(data); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// LoadDir 加载目录下所有 .yaml / .yml / .json 文件（不递归），按文件名顺序
+func (r *Registry) LoadDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, e := range entries {
+		switch strings.ToLower(filepath.Ext(e.Name())) {
+		case ".yaml", ".yml", ".json":
+		default:
+			continue
+		}
+		if e.IsDir() {
+			continue
+		}
+		errs = append(errs, r.LoadFile(filepath.Join(dir, e.Name())))
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Registry) Get(name string) (*Preset, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.presets[name]
+	return p, ok
+}
+
+// List 按名字排序返回全部 preset
+func (r *Registry) List() []*Preset {
+	r.mu.RLock()
+	out := make([]*Preset, 0, len(r.presets))
+	for _, p := range r.presets {
+		out = append(out, p)
+	}
+	r.mu.RUnlock()
+	sort.Slice(out, func(i, k int) bool { return out[i].Name < out[k].Name })
+	return out
+}
+
+// Describe 返回 preset 的可读说明
+func (r *Registry) Describe(name string) (string, error) {
+	p, ok := r.Get(name)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return p.Describe(), nil
+}
+
+// Instantiate 按名字和参数生成命令；参数值可以是对应类型，也可以是字符串（表单/命令行）
+func (r *Registry) Instantiate(name, input, output string, params map[string]any) (*ffmpeg.FFmpegCommand, error) {
+	p, ok := r.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return p.Build(input, output, params)
+}
+
+//go:embed builtin.yaml
+var builtinYAML []byte
+
+// Builtin 返回内置 preset 的新 Registry，与 ffmpeg.PresetXxx 函数生成的命令等价；
+// 可以继续 LoadDir 加入业务自己的 preset。builtin.yaml 由测试保证可以加载，这里失败只会是构建问题，直接 panic
+func Builtin() *Registry {
+	r := NewRegistry()
+	if err := r.Load(bytes.Clone(builtinYAML)); err != nil {
+		panic(err)
+	}
+	return r
+}
@@ -0,0 +1,145 @@
+package preset
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/LingByte/LingConvert/media/ffmpeg"
+)
+
+// 内置 preset 全部能加载，并且用默认参数能实例化；builtin.yaml 写坏时在这里失败而不是运行时 panic
+func TestBuiltin(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Load(builtinYAML); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.List()) == 0 {
+		t.Fatal("no builtin presets")
+	}
+	for _, p := range r.List() {
+		if _, err := p.Build("in.mp4", "out"+p.Extension, nil); err != nil {
+			t.Errorf("%s: %v", p.Name, err)
+		}
+		for _, pa := range p.Params {
+			if pa.Type == "" {
+				t.Errorf("%s: param %s type not normalized", p.Name, pa.Name)
+			}
+		}
+	}
+
+	// 与 ffmpeg.PresetXxx 等价
+	equiv := map[string]*ffmpeg.FFmpegCommand{
+		"transcode":   ffmpeg.PresetTranscodeMP4H264AAC("in.mp4", "out.mp4", 0, ""),
+		"remux":       ffmpeg.PresetRemux("in.mp4", "out.mp4"),
+		"extract_aac": ffmpeg.PresetExtractAAC("in.mp4", "out.mp4", "128k"),
+	}
+	for name, want := range equiv {
+		got, err := Builtin().Instantiate(name, "in.mp4", "out.mp4", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got.Args(), want.Args()) {
+			t.Errorf("%s: Args = %q, want %q", name, got.Args(), want.Args())
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	single := []byte("name: one\nparams: [{name: x}]\noutput: [-b:a, \"${x}\"]\n")
+	ps, err := Parse(single)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ps) != 1 || ps[0].Name != "one" || ps[0].Params[0].Type != TypeString {
+		t.Errorf("Parse single = %+v", ps[0])
+	}
+
+	js := []byte(`{"presets":[{"name":"a","params":[{"name":"n","type":"int","default":3}],"output":["-n","${n}"]}]}`)
+	if ps, err = Parse(js); err != nil || len(ps) != 1 || ps[0].Params[0].Type != TypeInt {
+		t.Errorf("Parse json = %+v, %v", ps, err)
+	}
+
+	if _, err := Parse([]byte("presets:\n  - name: a\n    ouptut: [-x]\n")); !errors.Is(err, ErrInvalid) {
+		t.Errorf("misspelled key: err = %v, want ErrInvalid", err)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	data := []byte(`
+presets:
+  - name: b
+    params: [{name: br, default: 96k}]
+    output: [-b:a, "${br}"]
+  - name: a
+    output: [-c, copy]
+`)
+	if err := r.Load(data); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, p := range r.List() {
+		names = append(names, p.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"a", "b"}) {
+		t.Errorf("List = %v", names)
+	}
+	if err := r.Load(data); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("reload = %v, want ErrDuplicate", err)
+	}
+	if err := r.Register(&Preset{Name: "a"}); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Register = %v, want ErrDuplicate", err)
+	}
+
+	// 一个无效则整个文件都不注册
+	bad := []byte("presets:\n  - name: c\n  - name: d\n    output: [\"${missing}\"]\n")
+	if err := r.Load(bad); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Load bad = %v, want ErrInvalid", err)
+	}
+	if _, ok := r.Get("c"); ok {
+		t.Error("preset c registered from an invalid file")
+	}
+
+	cmd, err := r.Instantiate("b", "in.mp4", "out.m4a", map[string]any{"br": "192k"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(cmd.Args(), " "); !strings.Contains(got, "-b:a 192k out.m4a") {
+		t.Errorf("Args = %s", got)
+	}
+	if _, err := r.Instantiate("zz", "in", "out", nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Instantiate unknown = %v, want ErrNotFound", err)
+	}
+	desc, err := r.Describe("b")
+	if err != nil || !strings.Contains(desc, "br (string) default=96k") {
+		t.Errorf("Describe = %q, %v", desc, err)
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a.yaml", "name: a\noutput: [-c, copy]\n")
+	write("b.json", `{"name":"b","output":["-vn"]}`)
+	write("notes.txt", "not a preset")
+	write("bad.yml", "name: bad\nparams: [{name: x, type: int, default: nope}]\n")
+
+	r := NewRegistry()
+	err := r.LoadDir(dir)
+	if !errors.Is(err, ErrInvalid) || !strings.Contains(err.Error(), "bad.yml") {
+		t.Errorf("LoadDir = %v, want the error from bad.yml", err)
+	}
+	for _, name := range []string{"a", "b"} {
+		if _, ok := r.Get(name); !ok {
+			t.Errorf("preset %s not loaded", name)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package proc
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// Executor 启动子进程的后端。ffmpeg.FFmpegTool / ffprobe.Tool 的 Executor 为 nil 时用 OS（真实进程）；
+// 单元测试可以换成 media/testing 里的脚本化假实现，不需要安装 ffmpeg
+type Executor interface {
+	// LookPath 把 "ffmpeg" 之类的名字解析成可执行文件路径
+	LookPath(file string) (string, error)
+	// Start 启动进程后立即返回；ctx 结束时按 Spec.Cancel 停止进程
+	Start(ctx context.Context, spec Spec) (Process, error)
+}
+
+// Spec 一次启动的参数，字段含义同 exec.Cmd
+type Spec struct {
+	Path string
+	Args []string // 不含 Path
+
+	Stdin      io.Reader
+	Stdout     io.Writer
+	Stderr     io.Writer
+	ExtraFiles []*os.File // 依次是子进程的 fd 3、4…
+
+	Limits Limits
+
+	// Cancel 在 ctx 结束时调用，nil 时杀掉进程（组）
+	Cancel func(p Process) error
+	// WaitDelay 取消后等待进程退出和 I/O 收尾的时间，超时强杀；0 不等待
+	WaitDelay time.Duration
+}
+
+// Process 运行中的子进程
+type Process interface {
+	Pid() int
+	// Signal 发信号；进程有独立进程组时发给整个组
+	Signal(sig os.Signal) error
+	// Wait 等待退出。非 0 退出时 error 实现 ExitCode() int（如 *exec.ExitError）；
+	// 同 exec.Cmd，ctx 结束后即使进程以 0 退出也返回错误。State 只要进程启动过就非 nil
+	Wait() (*State, error)
+}
+
+// State 进程退出状态和资源消耗
+type State struct {
+	ExitCode   int  // 被信号终止为 -1
+	Exited     bool // 进程自己退出（而不是被信号终止）
+	UserTime   time.Duration
+	SystemTime time.Duration
+	MaxRSS     int64 // 字节；平台不支持时为 0
+}
+
+// OS 用 os/exec 启动真实进程
+var OS Executor = osExecutor{}
+
+type osExecutor struct{}
+
+func (osExecutor) LookPath(file string) (string, error) { return exec.LookPath(file) }
+
+func (osExecutor) Start(ctx context.Context, spec Spec) (Process, error) {
+	cmd := exec.CommandContext(ctx, spec.Path, spec.Args...)
+	cmd.Stdin = spec.Stdin
+	cmd.Stdout = spec.Stdout
+	cmd.Stderr = spec.Stderr
+	cmd.ExtraFiles = spec.ExtraFiles
+	cmd.WaitDelay = spec.WaitDelay
+	spec.Limits.Prepare(cmd)
+
+	p := &osProcess{cmd: cmd, group: spec.Limits.ProcessGroup}
+	if spec.Cancel != nil {
+		cmd.Cancel = func() error { return spec.Cancel(p) }
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	if err := spec.Limits.Apply(cmd.Process); err != nil {
+		// 要求的限制没生效就不让它跑
+		_ = Kill(cmd.Process)
+		_ = cmd.Wait()
+		return nil, err
+	}
+	return p, nil
+}
+
+type osProcess struct {
+	cmd   *exec.Cmd
+	group bool
+}
+
+func (p *osProcess) Pid() int { return p.cmd.Process.Pid }
+
+func (p *osProcess) Signal(sig os.Signal) error { return Signal(p.cmd.Process, sig) }
+
+func (p *osProcess) Wait() (*State, error) {
+	err := p.cmd.Wait()
+	if p.group {
+		// 进程已退出，组里残留的子进程一并杀掉，否则它们持有的管道会让读取一直挂着
+		_ = KillGroup(p.cmd.Process.Pid)
+	}
+	ps := p.cmd.ProcessState
+	if ps == nil {
+		return &State{ExitCode: -1}, err
+	}
+	return &State{
+		ExitCode:   ps.ExitCode(),
+		Exited:     ps.Exited(),
+		UserTime:   ps.UserTime(),
+		SystemTime: ps.SystemTime(),
+		MaxRSS:     MaxRSS(ps),
+	}, err
+}
+
+// Run 启动并等待进程结束
+func Run(ctx context.Context, e Executor, spec Spec) error {
+	p, err := e.Start(ctx, spec)
+	if err != nil {
+		return err
+	}
+	_, err = p.Wait()
+	return err
+}
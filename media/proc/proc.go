@@ -0,0 +1,96 @@
+// Package proc 管理 ffmpeg / ffprobe 子进程：独立进程组、nice/ionice 优先级和 RLIMIT 资源限制。
+// 一般通过 ffmpeg.FFmpegTool.Limits / ffprobe.Tool.Limits 使用。
+package proc
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// IOClass 对应 ionice 的调度类
+type IOClass int
+
+const (
+	IONone       IOClass = iota // 不调整
+	IORealtime                  // 需要 root
+	IOBestEffort                // 配合 IOLevel 0~7，数字越小优先级越高
+	IOIdle                      // 只在磁盘空闲时读写
+)
+
+// Limits 子进程的隔离和资源限制，零值表示全部不启用
+type Limits struct {
+	// ProcessGroup 让子进程自成一个进程组，取消/超时时整组一起杀掉；
+	// Linux 上还会设置 Pdeathsig：启动它的 OS 线程退出时子进程收到 SIGKILL，不会变成孤儿。
+	// Go 运行时可能在父进程存活时回收该线程，需要可靠生效时在 Start 前 runtime.LockOSThread
+	ProcessGroup bool
+
+	// Nice 1~19 降低 CPU 优先级（负数需要权限），0 不调整
+	Nice int
+	// IOClass / IOLevel 对应 ionice -c / -n，仅 Linux
+	IOClass IOClass
+	IOLevel int
+
+	// Threads 限制 ffmpeg 编解码和滤镜线程数（-threads / -filter_threads），0 不限制；ffprobe 忽略
+	Threads int
+
+	// MaxMemory 虚拟内存上限（RLIMIT_AS），字节，0 不限制；超出时分配失败，错误归类为 ErrResourceLimit
+	MaxMemory uint64
+	// MaxCPUTime CPU 时间上限（RLIMIT_CPU），按秒向上取整，0 不限制；超出时进程收到 SIGXCPU
+	MaxCPUTime time.Duration
+}
+
+// ErrUnsupported 当前平台不支持请求的限制
+var ErrUnsupported = errors.ErrUnsupported
+
+// Prepare 在 Start 之前调用：设置进程组，并让 CommandContext 的默认 kill 作用于整个组。
+// 之后若再替换 cmd.Cancel，应使用本包的 Signal / Kill。
+func (l Limits) Prepare(cmd *exec.Cmd) {
+	if !l.ProcessGroup {
+		return
+	}
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error { return Kill(cmd.Process) }
+}
+
+// Apply 在 Start 之后调用，设置优先级和 rlimit。
+// 限制是进程启动后才设置的，启动到 Apply 返回之间子进程不受限制（通常只有几毫秒）。
+// 返回错误时调用方应杀掉进程：请求的限制没有生效。
+func (l Limits) Apply(p *os.Process) error {
+	if l.Nice == 0 && l.IOClass == IONone && l.MaxMemory == 0 && l.MaxCPUTime <= 0 {
+		return nil
+	}
+	return apply(p.Pid, l)
+}
+
+// cpuSeconds MaxCPUTime 向上取整到秒
+func (l Limits) cpuSeconds() uint64 {
+	if l.MaxCPUTime <= 0 {
+		return 0
+	}
+	return uint64((l.MaxCPUTime + time.Second - 1) / time.Second)
+}
+
+// Signal 给进程发信号；进程是自己进程组的组长时发给整个组
+func Signal(p *os.Process, sig os.Signal) error {
+	return signal(p, sig)
+}
+
+// Kill 杀掉进程（及其进程组）
+func Kill(p *os.Process) error {
+	return signal(p, os.Kill)
+}
+
+// KillGroup 杀掉以 pgid 为组号的整个进程组，组长已退出也有效；非 unix 平台什么都不做
+func KillGroup(pgid int) error {
+	return killGroup(pgid)
+}
+
+// MaxRSS 进程峰值常驻内存（字节），来自 ProcessState.SysUsage；平台不支持时返回 0
+func MaxRSS(ps *os.ProcessState) int64 {
+	if ps == nil {
+		return 0
+	}
+	return maxRSS(ps)
+}
@@ -0,0 +1,27 @@
+//go:build unix && !linux
+
+package proc
+
+import (
+	"fmt"
+	"os/exec"
+	"syscall"
+)
+
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// 非 Linux 的 unix 没有 prlimit / ioprio，只支持 nice
+func apply(pid int, l Limits) error {
+	if l.IOClass != IONone || l.MaxMemory > 0 || l.MaxCPUTime > 0 {
+		return fmt.Errorf("ionice / rlimit: %w", ErrUnsupported)
+	}
+	if err := syscall.Setpriority(syscall.PRIO_PROCESS, pid, l.Nice); err != nil {
+		return fmt.Errorf("set nice %d: %w", l.Nice, err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+//go:build linux
+
+package proc
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// setProcessGroup 新进程组，父进程退出时内核给子进程发 SIGKILL。
+// 注意 Pdeathsig 在 fork 子进程的那个 OS 线程退出时就会触发，而不是整个父进程退出时；
+// Go 的线程会被运行时回收，调用方要依赖它时需要在同一个 goroutine 里 runtime.LockOSThread 后再 Start
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+	cmd.SysProcAttr.Pdeathsig = syscall.SIGKILL
+}
+
+const (
+	ioprioWhoProcess = 1
+	ioprioClassShift = 13
+)
+
+// apply 在子进程启动后用 prlimit / setpriority / ioprio_set 设置限制。
+// SysProcAttr 没有 rlimit / nice 字段，只能事后设置：从 exec 到这里有一个很短的窗口子进程不受限制，
+// 窗口内 ffmpeg 还在加载，占用的内存和 CPU 可以忽略
+func apply(pid int, l Limits) error {
+	// rlimit 是整个进程的；nice / ioprio 在 Linux 上按线程生效，所以对已有的每个线程都设置一遍，
+	// 之后新建的线程会继承
+	if l.MaxMemory > 0 {
+		rl := unix.Rlimit{Cur: l.MaxMemory, Max: l.MaxMemory}
+		if err := unix.Prlimit(pid, unix.RLIMIT_AS, &rl, nil); err != nil {
+			return fmt.Errorf("set RLIMIT_AS: %w", err)
+		}
+	}
+	if secs := l.cpuSeconds(); secs > 0 {
+		// 软限制到了先发 SIGXCPU，硬限制多留 1 秒再 SIGKILL
+		rl := unix.Rlimit{Cur: secs, Max: secs + 1}
+		if err := unix.Prlimit(pid, unix.RLIMIT_CPU, &rl, nil); err != nil {
+			return fmt.Errorf("set RLIMIT_CPU: %w", err)
+		}
+	}
+	if l.Nice == 0 && l.IOClass == IONone {
+		return nil
+	}
+	for _, tid := range threads(pid) {
+		if l.Nice != 0 {
+			if err := unix.Setpriority(unix.PRIO_PROCESS, tid, l.Nice); err != nil && err != unix.ESRCH {
+				return fmt.Errorf("set nice %d: %w", l.Nice, err)
+			}
+		}
+		if l.IOClass != IONone {
+			prio := int(l.IOClass)<<ioprioClassShift | l.IOLevel
+			_, _, errno := unix.Syscall(unix.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), uintptr(prio))
+			if errno != 0 && errno != unix.ESRCH {
+				return fmt.Errorf("set ionice: %w", errno)
+			}
+		}
+	}
+	return nil
+}
+
+// threads 列出进程当前的线程 id；读不到 /proc 时只返回 pid 本身
+func threads(pid int) []int {
+	entries, err := os.ReadDir("/proc/" + strconv.Itoa(pid) + "/task")
+	if err != nil {
+		return []int{pid}
+	}
+	out := make([]int, 0, len(entries))
+	for _, e := range entries {
+		if tid, err := strconv.Atoi(e.Name()); err == nil {
+			out = append(out, tid)
+		}
+	}
+	return out
+}
@@ -0,0 +1,19 @@
+//go:build !unix
+
+package proc
+
+import (
+	"os"
+	"os/exec"
+)
+
+// 非 unix 平台没有进程组信号，只杀进程本身
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func apply(pid int, l Limits) error { return ErrUnsupported }
+
+func signal(p *os.Process, sig os.Signal) error { return p.Signal(sig) }
+
+func killGroup(pgid int) error { return nil }
+
+func maxRSS(ps *os.ProcessState) int64 { return 0 }
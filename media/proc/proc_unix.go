@@ -0,0 +1,42 @@
+//go:build unix
+
+package proc
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+)
+
+func signal(p *os.Process, sig os.Signal) error {
+	s, ok := sig.(syscall.Signal)
+	if !ok {
+		return p.Signal(sig)
+	}
+	if pgid, err := syscall.Getpgid(p.Pid); err == nil && pgid == p.Pid {
+		if err := syscall.Kill(-pgid, s); err != syscall.ESRCH {
+			return err
+		}
+		return os.ErrProcessDone
+	}
+	return p.Signal(sig)
+}
+
+func killGroup(pgid int) error {
+	if err := syscall.Kill(-pgid, syscall.SIGKILL); err != nil && err != syscall.ESRCH {
+		return err
+	}
+	return nil
+}
+
+func maxRSS(ps *os.ProcessState) int64 {
+	ru, ok := ps.SysUsage().(*syscall.Rusage)
+	if !ok {
+		return 0
+	}
+	// macOS 的 ru_maxrss 单位是字节，Linux / BSD 是 KB
+	if runtime.GOOS == "darwin" || runtime.GOOS == "ios" {
+		return int64(ru.Maxrss)
+	}
+	return int64(ru.Maxrss) * 1024
+}
@@ -0,0 +1,431 @@
+// Package queue 在 ffmpeg.FFmpegTool 之上提供进程内任务队列：
+// 固定数量的 worker 并发执行，支持 FIFO / 优先级排序、进度订阅、按 ID 取消和优雅关闭。
+package queue
+
+import (
+	"container/heap"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/LingByte/LingConvert/media/ffmpeg"
+)
+
+var (
+	ErrClosed     = errors.New("queue: closed")
+	ErrNotFound   = errors.New("queue: job not found")
+	ErrDuplicate  = errors.New("queue: duplicate job id")
+	ErrNotRunning = errors.New("queue: job already finished")
+)
+
+type Status string
+
+const (
+	StatusQueued   Status = "queued"
+	StatusRunning  Status = "running"
+	StatusDone     Status = "done"
+	StatusFailed   Status = "failed"
+	StatusCanceled Status = "canceled"
+)
+
+// Terminal 表示任务已结束，不会再变化
+func (s Status) Terminal() bool {
+	return s == StatusDone || s == StatusFailed || s == StatusCanceled
+}
+
+type Ordering int
+
+const (
+	FIFO     Ordering = iota // 先提交先执行
+	Priority                 // Priority 大的先执行，相同则 FIFO
+)
+
+type Options struct {
+	Workers  int // 同时运行的 ffmpeg 进程数，默认 1
+	Ordering Ordering
+	// 订阅者 channel 缓冲，慢订阅者会丢进度事件（状态变化事件不丢），默认 16
+	SubscriberBuffer int
+}
+
+// Request 提交的任务
+type Request struct {
+	ID       string // 可选，空则自动生成
+	Command  *ffmpeg.FFmpegCommand
+	Priority int
+
+	// 执行参数；OnProgress 会在队列广播之后调用
+	RunOptions ffmpeg.RunOptions
+}
+
+// JobInfo 任务快照
+type JobInfo struct {
+	ID         string
+	Status     Status
+	Priority   int
+	Progress   ffmpeg.FFmpegProgress
+	Err        error
+	Result     *ffmpeg.RunResult // 运行结束后才有：耗时、CPU、内存、退出码
+	CreatedAt  time.Time
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+type EventType string
+
+const (
+	EventStatus   EventType = "status"
+	EventProgress EventType = "progress"
+)
+
+type Event struct {
+	Type EventType
+	Job  JobInfo
+}
+
+type job struct {
+	info   JobInfo
+	req    Request
+	seq    uint64
+	index  int // heap index，-1 表示不在队列里
+	cancel context.CancelFunc
+	subs   map[chan Event]struct{}
+}
+
+type Queue struct {
+	tool *ffmpeg.FFmpegTool
+	opt  Options
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	pending jobHeap
+	jobs    map[string]*job
+	seq     uint64
+	closed  bool
+
+	baseCtx    context.Context
+	cancelBase context.CancelFunc
+	workers    sync.WaitGroup
+}
+
+// New 创建队列并启动 worker
+func New(tool *ffmpeg.FFmpegTool, opt Options) *Queue {
+	if opt.Workers <= 0 {
+		opt.Workers = 1
+	}
+	if opt.SubscriberBuffer <= 0 {
+		opt.SubscriberBuffer = 16
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &Queue{
+		tool:       tool,
+		opt:        opt,
+		jobs:       map[string]*job{},
+		baseCtx:    ctx,
+		cancelBase: cancel,
+	}
+	q.pending.ordering = opt.Ordering
+	q.cond = sync.NewCond(&q.mu)
+	q.workers.Add(opt.Workers)
+	for i := 0; i < opt.Workers; i++ {
+		go q.worker()
+	}
+	return q
+}
+
+func (q *Queue) Submit(req Request) (string, error) {
+	if req.Command == nil {
+		return "", errors.New("queue: Command is required")
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return "", ErrClosed
+	}
+	if req.ID == "" {
+		req.ID = newID()
+	}
+	if _, ok := q.jobs[req.ID]; ok {
+		return "", fmt.Errorf("%w: %s", ErrDuplicate, req.ID)
+	}
+	q.seq++
+	j := &job{
+		info: JobInfo{
+			ID:        req.ID,
+			Status:    StatusQueued,
+			Priority:  req.Priority,
+			CreatedAt: time.Now(),
+		},
+		req: req,
+		seq: q.seq,
+	}
+	q.jobs[req.ID] = j
+	heap.Push(&q.pending, j)
+	q.cond.Signal()
+	return req.ID, nil
+}
+
+func (q *Queue) Get(id string) (JobInfo, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	j, ok := q.jobs[id]
+	if !ok {
+		return JobInfo{}, false
+	}
+	return j.info, true
+}
+
+// List 返回所有任务快照（包括已结束、尚未 Forget 的）
+func (q *Queue) List() []JobInfo {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	out := make([]JobInfo, 0, len(q.jobs))
+	for _, j := range q.jobs {
+		out = append(out, j.info)
+	}
+	return out
+}
+
+// Forget 删除已结束任务的记录
+func (q *Queue) Forget(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	j, ok := q.jobs[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if !j.info.Status.Terminal() {
+		return fmt.Errorf("queue: job %s is still %s", id, j.info.Status)
+	}
+	delete(q.jobs, id)
+	return nil
+}
+
+// Subscribe 订阅任务事件。channel 立即收到一次当前状态；任务结束后发送最终状态并关闭。
+// 调用返回的 cancel 可提前退订。
+func (q *Queue) Subscribe(id string) (<-chan Event, func(), error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	j, ok := q.jobs[id]
+	if !ok {
+		return nil, nil, ErrNotFound
+	}
+	ch := make(chan Event, q.opt.SubscriberBuffer)
+	ch <- Event{Type: EventStatus, Job: j.info}
+	if j.info.Status.Terminal() {
+		close(ch)
+		return ch, func() {}, nil
+	}
+	if j.subs == nil {
+		j.subs = map[chan Event]struct{}{}
+	}
+	j.subs[ch] = struct{}{}
+	unsubscribe := func() {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		if _, ok := j.subs[ch]; ok {
+			delete(j.subs, ch)
+			close(ch)
+		}
+	}
+	return ch, unsubscribe, nil
+}
+
+// Cancel 取消排队中或运行中的任务
+func (q *Queue) Cancel(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	j, ok := q.jobs[id]
+	if !ok {
+		return ErrNotFound
+	}
+	switch j.info.Status {
+	case StatusQueued:
+		heap.Remove(&q.pending, j.index)
+		q.finishLocked(j, StatusCanceled, ffmpeg.ErrCanceled)
+	case StatusRunning:
+		j.cancel()
+	default:
+		return ErrNotRunning
+	}
+	return nil
+}
+
+// Shutdown 停止接收新任务。
+// drain=true 时等排队和运行中的任务全部完成；否则取消排队任务并中止运行中的任务。
+// ctx 到期时会中止剩余任务并返回 ctx.Err()。
+func (q *Queue) Shutdown(ctx context.Context, drain bool) error {
+	q.mu.Lock()
+	q.closed = true
+	if !drain {
+		q.cancelPendingLocked()
+		q.cancelBase()
+	}
+	q.cond.Broadcast()
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		q.cancelBase()
+		return nil
+	case <-ctx.Done():
+		q.mu.Lock()
+		q.cancelPendingLocked()
+		q.mu.Unlock()
+		q.cancelBase()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (q *Queue) cancelPendingLocked() {
+	for q.pending.Len() > 0 {
+		j := heap.Pop(&q.pending).(*job)
+		q.finishLocked(j, StatusCanceled, ffmpeg.ErrCanceled)
+	}
+}
+
+func (q *Queue) worker() {
+	defer q.workers.Done()
+	for {
+		q.mu.Lock()
+		for q.pending.Len() == 0 && !q.closed {
+			q.cond.Wait()
+		}
+		if q.pending.Len() == 0 {
+			q.mu.Unlock()
+			return
+		}
+		j := heap.Pop(&q.pending).(*job)
+		ctx, cancel := context.WithCancel(q.baseCtx)
+		j.cancel = cancel
+		j.info.Status = StatusRunning
+		j.info.StartedAt = time.Now()
+		q.broadcastLocked(j, EventStatus)
+		q.mu.Unlock()
+
+		q.run(ctx, j)
+		cancel()
+	}
+}
+
+func (q *Queue) run(ctx context.Context, j *job) {
+	opt := j.req.RunOptions
+	userCb := opt.OnProgress
+	opt.OnProgress = func(p ffmpeg.FFmpegProgress) error {
+		q.mu.Lock()
+		j.info.Progress = p
+		q.broadcastLocked(j, EventProgress)
+		q.mu.Unlock()
+		if userCb != nil {
+			return userCb(p)
+		}
+		return nil
+	}
+
+	res, err := q.tool.RunWithResult(ctx, j.req.Command, opt)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if res != nil {
+		j.info.Progress = res.Progress
+		j.info.Result = res
+	}
+	switch {
+	case err == nil:
+		q.finishLocked(j, StatusDone, nil)
+	case (errors.Is(err, ffmpeg.ErrCanceled) || errors.Is(err, ffmpeg.ErrStoppedEarly)) && ctx.Err() != nil:
+		q.finishLocked(j, StatusCanceled, err)
+	default:
+		q.finishLocked(j, StatusFailed, err)
+	}
+}
+
+func (q *Queue) finishLocked(j *job, st Status, err error) {
+	j.info.Status = st
+	j.info.Err = err
+	j.info.FinishedAt = time.Now()
+	q.broadcastLocked(j, EventStatus)
+	for ch := range j.subs {
+		close(ch)
+	}
+	j.subs = nil
+}
+
+func (q *Queue) broadcastLocked(j *job, typ EventType) {
+	ev := Event{Type: typ, Job: j.info}
+	for ch := range j.subs {
+		if typ == EventStatus {
+			// 状态事件不丢：缓冲满时挤掉一个旧事件
+			select {
+			case ch <- ev:
+			default:
+				select {
+				case <-ch:
+				default:
+				}
+				select {
+				case ch <- ev:
+				default:
+				}
+			}
+			continue
+		}
+		select {
+		case ch <- ev:
+		default:
+			// slow subscriber -> drop
+		}
+	}
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// jobHeap 按 Ordering 排序的待执行任务
+type jobHeap struct {
+	ordering Ordering
+	items    []*job
+}
+
+func (h *jobHeap) Len() int { return len(h.items) }
+
+func (h *jobHeap) Less(i, k int) bool {
+	a, b := h.items[i], h.items[k]
+	if h.ordering == Priority && a.info.Priority != b.info.Priority {
+		return a.info.Priority > b.info.Priority
+	}
+	return a.seq < b.seq
+}
+
+func (h *jobHeap) Swap(i, k int) {
+	h.items[i], h.items[k] = h.items[k], h.items[i]
+	h.items[i].index = i
+	h.items[k].index = k
+}
+
+func (h *jobHeap) Push(x any) {
+	j := x.(*job)
+	j.index = len(h.items)
+	h.items = append(h.items, j)
+}
+
+func (h *jobHeap) Pop() any {
+	n := len(h.items)
+	j := h.items[n-1]
+	h.items[n-1] = nil
+	h.items = h.items[:n-1]
+	j.index = -1
+	return j
+}
@@ -0,0 +1,318 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LingByte/LingConvert/media/ffmpeg"
+	"github.com/LingByte/LingConvert/media/queue"
+	fftest "github.com/LingByte/LingConvert/media/testing"
+)
+
+func command(name string) *ffmpeg.FFmpegCommand {
+	return ffmpeg.NewFFmpegCommand().Input(name + ".mp4").Output("out.mp4")
+}
+
+// started 按启动顺序返回已经启动的任务名（输入文件名去掉 .mp4）
+func started(fake *fftest.Executor) []string {
+	var out []string
+	for _, c := range fake.Calls() {
+		for i := 0; i+1 < len(c.Args); i++ {
+			if c.Args[i] == "-i" {
+				out = append(out, strings.TrimSuffix(c.Args[i+1], ".mp4"))
+			}
+		}
+	}
+	return out
+}
+
+func waitStatus(t *testing.T, q *queue.Queue, id string, want queue.Status) queue.JobInfo {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		info, ok := q.Get(id)
+		if ok && info.Status == want {
+			return info
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s: status = %s, want %s", id, info.Status, want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func submit(t *testing.T, q *queue.Queue, id string, priority int) {
+	t.Helper()
+	if _, err := q.Submit(queue.Request{ID: id, Command: command(id), Priority: priority}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOrdering(t *testing.T) {
+	tests := []struct {
+		ordering queue.Ordering
+		want     []string
+	}{
+		{queue.FIFO, []string{"block", "a", "b", "c", "d"}},
+		{queue.Priority, []string{"block", "b", "d", "c", "a"}},
+	}
+	for _, tt := range tests {
+		fake := fftest.NewExecutor()
+		fake.On("-i block.mp4").Hang()
+		fake.On()
+		q := queue.New(&ffmpeg.FFmpegTool{Executor: fake}, queue.Options{Workers: 1, Ordering: tt.ordering})
+
+		// 先占住唯一的 worker，其余任务都在排队时才提交
+		submit(t, q, "block", 100)
+		waitStatus(t, q, "block", queue.StatusRunning)
+		submit(t, q, "a", 1)
+		submit(t, q, "b", 5)
+		submit(t, q, "c", 3)
+		submit(t, q, "d", 5)
+		if err := q.Cancel("block"); err != nil {
+			t.Fatal(err)
+		}
+		if err := q.Shutdown(context.Background(), true); err != nil {
+			t.Fatal(err)
+		}
+		if got := started(fake); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ordering %d: started %v, want %v", tt.ordering, got, tt.want)
+		}
+	}
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	fake := fftest.NewExecutor()
+	fake.On("-i").Hang()
+	q := queue.New(&ffmpeg.FFmpegTool{Executor: fake}, queue.Options{Workers: 2})
+	defer q.Shutdown(context.Background(), false)
+
+	ids := []string{"j0", "j1", "j2", "j3", "j4"}
+	for _, id := range ids {
+		submit(t, q, id, 0)
+	}
+	running := func() []string {
+		var out []string
+		for _, info := range q.List() {
+			if info.Status == queue.StatusRunning {
+				out = append(out, info.ID)
+			}
+		}
+		return out
+	}
+	for n := 0; n < len(ids); n++ {
+		waitStatus(t, q, ids[n], queue.StatusRunning)
+		time.Sleep(20 * time.Millisecond)
+		if r := running(); len(r) > 2 {
+			t.Fatalf("running = %v, want at most 2", r)
+		}
+		if n+2 < len(ids) {
+			if info, _ := q.Get(ids[n+2]); info.Status != queue.StatusQueued {
+				t.Fatalf("%s = %s, want queued", ids[n+2], info.Status)
+			}
+		}
+		if err := q.Cancel(ids[n]); err != nil {
+			t.Fatal(err)
+		}
+		waitStatus(t, q, ids[n], queue.StatusCanceled)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	fake := fftest.NewExecutor()
+	fake.On("-i").Hang()
+	q := queue.New(&ffmpeg.FFmpegTool{Executor: fake}, queue.Options{Workers: 1})
+	defer q.Shutdown(context.Background(), false)
+
+	submit(t, q, "running", 0)
+	waitStatus(t, q, "running", queue.StatusRunning)
+	submit(t, q, "queued", 0)
+
+	if err := q.Cancel("queued"); err != nil {
+		t.Fatal(err)
+	}
+	info, _ := q.Get("queued")
+	if info.Status != queue.StatusCanceled || !errors.Is(info.Err, ffmpeg.ErrCanceled) || !info.StartedAt.IsZero() {
+		t.Errorf("queued job after cancel = %+v", info)
+	}
+
+	if err := q.Cancel("running"); err != nil {
+		t.Fatal(err)
+	}
+	info = waitStatus(t, q, "running", queue.StatusCanceled)
+	if info.Err == nil || info.Result == nil {
+		t.Errorf("running job after cancel = %+v", info)
+	}
+	if got := started(fake); !reflect.DeepEqual(got, []string{"running"}) {
+		t.Errorf("started %v, want only the running job", got)
+	}
+
+	if err := q.Cancel("running"); !errors.Is(err, queue.ErrNotRunning) {
+		t.Errorf("second cancel = %v, want ErrNotRunning", err)
+	}
+	if err := q.Cancel("missing"); !errors.Is(err, queue.ErrNotFound) {
+		t.Errorf("cancel unknown = %v, want ErrNotFound", err)
+	}
+}
+
+func TestShutdownDrain(t *testing.T) {
+	fake := fftest.NewExecutor()
+	fake.On("-i").Progress(fftest.ProgressBlock(time.Second, true)...).Interval(30 * time.Millisecond)
+	q := queue.New(&ffmpeg.FFmpegTool{Executor: fake}, queue.Options{Workers: 1})
+
+	ids := []string{"a", "b", "c"}
+	for _, id := range ids {
+		if _, err := q.Submit(queue.Request{ID: id, Command: command(id),
+			RunOptions: ffmpeg.RunOptions{OnProgress: func(ffmpeg.FFmpegProgress) error { return nil }}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := q.Shutdown(context.Background(), true); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range ids {
+		if info, _ := q.Get(id); info.Status != queue.StatusDone {
+			t.Errorf("%s = %s (%v), want done", id, info.Status, info.Err)
+		}
+	}
+	if _, err := q.Submit(queue.Request{Command: command("late")}); !errors.Is(err, queue.ErrClosed) {
+		t.Errorf("submit after shutdown = %v, want ErrClosed", err)
+	}
+}
+
+func TestShutdownDrainDeadline(t *testing.T) {
+	fake := fftest.NewExecutor()
+	fake.On("-i").Hang()
+	q := queue.New(&ffmpeg.FFmpegTool{Executor: fake}, queue.Options{Workers: 1})
+	submit(t, q, "a", 0)
+	waitStatus(t, q, "a", queue.StatusRunning)
+	submit(t, q, "b", 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := q.Shutdown(ctx, true); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown = %v, want DeadlineExceeded", err)
+	}
+	for _, id := range []string{"a", "b"} {
+		if info, _ := q.Get(id); info.Status != queue.StatusCanceled {
+			t.Errorf("%s = %s, want canceled", id, info.Status)
+		}
+	}
+}
+
+func TestShutdownCancel(t *testing.T) {
+	fake := fftest.NewExecutor()
+	fake.On("-i").Hang()
+	q := queue.New(&ffmpeg.FFmpegTool{Executor: fake}, queue.Options{Workers: 1})
+	submit(t, q, "running", 0)
+	waitStatus(t, q, "running", queue.StatusRunning)
+	submit(t, q, "queued", 0)
+
+	done := make(chan error, 1)
+	go func() { done <- q.Shutdown(context.Background(), false) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Shutdown(cancel) did not stop the running job")
+	}
+	for _, id := range []string{"running", "queued"} {
+		if info, _ := q.Get(id); info.Status != queue.StatusCanceled {
+			t.Errorf("%s = %s, want canceled", id, info.Status)
+		}
+	}
+	if got := started(fake); !reflect.DeepEqual(got, []string{"running"}) {
+		t.Errorf("started %v, want only the running job", got)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	fake := fftest.NewExecutor()
+	fake.On("-i block.mp4").Hang()
+	var lines []string
+	lines = append(lines, fftest.ProgressBlock(time.Second, false)...)
+	lines = append(lines, fftest.ProgressBlock(2*time.Second, true)...)
+	fake.On("-i").Progress(lines...).Interval(10 * time.Millisecond)
+	q := queue.New(&ffmpeg.FFmpegTool{Executor: fake}, queue.Options{Workers: 1, SubscriberBuffer: 64})
+	defer q.Shutdown(context.Background(), false)
+
+	submit(t, q, "block", 0)
+	waitStatus(t, q, "block", queue.StatusRunning)
+	if _, err := q.Submit(queue.Request{ID: "job", Command: command("job"),
+		RunOptions: ffmpeg.RunOptions{OnProgress: func(ffmpeg.FFmpegProgress) error { return nil }}}); err != nil {
+		t.Fatal(err)
+	}
+	events, _, err := q.Subscribe("job")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Cancel("block"); err != nil {
+		t.Fatal(err)
+	}
+
+	var statuses []queue.Status
+	progress := 0
+	timeout := time.After(3 * time.Second)
+	for open := true; open; {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				open = false
+				break
+			}
+			if ev.Type == queue.EventProgress {
+				progress++
+			} else {
+				statuses = append(statuses, ev.Job.Status)
+			}
+		case <-timeout:
+			t.Fatal("subscriber channel was not closed")
+		}
+	}
+	want := []queue.Status{queue.StatusQueued, queue.StatusRunning, queue.StatusDone}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Errorf("status events = %v, want %v", statuses, want)
+	}
+	if progress == 0 {
+		t.Error("no progress events")
+	}
+
+	// 已结束的任务：收到一次最终状态后关闭
+	events, _, err = q.Subscribe("job")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev := <-events; ev.Job.Status != queue.StatusDone {
+		t.Errorf("late subscribe = %+v", ev)
+	}
+	if _, ok := <-events; ok {
+		t.Error("late subscriber channel not closed")
+	}
+	if _, _, err := q.Subscribe("missing"); !errors.Is(err, queue.ErrNotFound) {
+		t.Errorf("subscribe unknown = %v, want ErrNotFound", err)
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	fake := fftest.NewExecutor()
+	fake.On("-i").Hang()
+	q := queue.New(&ffmpeg.FFmpegTool{Executor: fake}, queue.Options{Workers: 1})
+	defer q.Shutdown(context.Background(), false)
+
+	submit(t, q, "a", 0)
+	events, unsubscribe, err := q.Subscribe("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-events
+	unsubscribe()
+	unsubscribe() // 可以重复调用
+	for range events {
+	}
+}
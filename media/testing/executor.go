@@ -0,0 +1,442 @@
+// Package testing 提供脚本化的假 proc.Executor：按命令行匹配脚本，回放预设的 stdout、stderr、
+// 进度行和退出码，不需要安装 ffmpeg / ffprobe 就能给基于 LingConvert 的代码写确定性的单元测试。
+//
+//	fake := testing.NewExecutor()
+//	fake.On("-i", "in.mp4").Progress(testing.ProgressBlock(time.Second, false)...).Exit(0)
+//	tool := &ffmpeg.FFmpegTool{Executor: fake}
+package testing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LingByte/LingConvert/media/proc"
+)
+
+// Executor 实现 proc.Executor。按注册顺序匹配脚本，第一个匹配的生效；
+// 没有脚本匹配的 "-version" 调用自动回一个版本号，其余调用 Start 返回错误。
+type Executor struct {
+	// LookPathErr 非 nil 时 LookPath 返回它，模拟未安装
+	LookPathErr error
+
+	mu      sync.Mutex
+	scripts []*Script
+	calls   []*record
+	nextPid int
+}
+
+func NewExecutor() *Executor {
+	return &Executor{nextPid: 10000}
+}
+
+// On 注册脚本：命令行（不含可执行文件）包含全部 contains 时匹配；不传则匹配任何调用
+func (e *Executor) On(contains ...string) *Script {
+	return e.OnFunc(func(path string, args []string) bool {
+		line := " " + strings.Join(args, " ") + " "
+		for _, c := range contains {
+			if !strings.Contains(line, c) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// OnFunc 用自定义条件注册脚本
+func (e *Executor) OnFunc(match func(path string, args []string) bool) *Script {
+	s := &Script{match: match}
+	e.mu.Lock()
+	e.scripts = append(e.scripts, s)
+	e.mu.Unlock()
+	return s
+}
+
+// Calls 返回已发生的调用（按启动顺序）
+func (e *Executor) Calls() []Call {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	out := make([]Call, 0, len(e.calls))
+	for _, r := range e.calls {
+		out = append(out, r.snapshot())
+	}
+	return out
+}
+
+func (e *Executor) LookPath(file string) (string, error) {
+	if e.LookPathErr != nil {
+		return "", e.LookPathErr
+	}
+	return file, nil
+}
+
+func (e *Executor) Start(ctx context.Context, spec proc.Spec) (proc.Process, error) {
+	e.mu.Lock()
+	var script *Script
+	for _, s := range e.scripts {
+		if s.match(spec.Path, spec.Args) {
+			script = s
+			break
+		}
+	}
+	if script == nil && len(spec.Args) == 1 && spec.Args[0] == "-version" {
+		script = &Script{stdout: filepath.Base(spec.Path) + " version 6.1-fake Copyright (c) the FFmpeg developers\n"}
+	}
+	e.nextPid++
+	rec := &record{call: Call{Path: spec.Path, Args: append([]string(nil), spec.Args...), Pid: e.nextPid}}
+	e.calls = append(e.calls, rec)
+	e.mu.Unlock()
+
+	if script == nil {
+		return nil, fmt.Errorf("testing: no script matches %s %s", spec.Path, strings.Join(spec.Args, " "))
+	}
+	if script.startErr != nil {
+		return nil, script.startErr
+	}
+
+	p := &process{
+		rec:     rec,
+		script:  script,
+		spec:    spec,
+		signals: make(chan os.Signal, 16),
+		done:    make(chan struct{}),
+	}
+	go p.run()
+	go p.watch(ctx)
+	return p, nil
+}
+
+// Call 一次调用的记录
+type Call struct {
+	Path    string
+	Args    []string
+	Pid     int
+	Stdin   []byte      // 进程读到的 stdin
+	Signals []os.Signal // 收到的信号（stdin 里的 q 不算）
+}
+
+// record 进程运行期间不断追加 stdin / 信号的 Call
+type record struct {
+	mu   sync.Mutex
+	call Call
+}
+
+func (r *record) snapshot() Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c := r.call
+	c.Stdin = append([]byte(nil), c.Stdin...)
+	c.Signals = append([]os.Signal(nil), c.Signals...)
+	return c
+}
+
+// Script 一个假进程的行为，用链式方法配置
+type Script struct {
+	match    func(path string, args []string) bool
+	stdout   string
+	stderr   string
+	progress []string
+	interval time.Duration
+	exit     int
+	hang     bool
+	startErr error
+}
+
+// Stdout 进程写到 stdout 的内容（进度走 pipe:1 时写在进度之前）
+func (s *Script) Stdout(out string) *Script { s.stdout = out; return s }
+
+// Stderr 进程写到 stderr 的内容
+func (s *Script) Stderr(out string) *Script { s.stderr = out; return s }
+
+// Progress 追加 key=value 进度行，写到命令行 -progress pipe:N 指定的位置（1=stdout，2=stderr，3=第一个 ExtraFiles）
+func (s *Script) Progress(lines ...string) *Script {
+	s.progress = append(s.progress, lines...)
+	return s
+}
+
+// Interval 每个进度块（progress= 行）之后停顿的时间
+func (s *Script) Interval(d time.Duration) *Script { s.interval = d; return s }
+
+// Exit 输出完后以 code 退出，默认 0
+func (s *Script) Exit(code int) *Script { s.exit = code; return s }
+
+// Hang 输出完后不退出，直到收到 q（stdin）、SIGINT 或被杀，模拟长时间转码
+func (s *Script) Hang() *Script { s.hang = true; return s }
+
+// FailStart Start 直接返回 err，模拟可执行文件无法启动
+func (s *Script) FailStart(err error) *Script { s.startErr = err; return s }
+
+// ProgressBlock 生成一块 ffmpeg -progress 输出
+func ProgressBlock(outTime time.Duration, done bool) []string {
+	state := "continue"
+	if done {
+		state = "end"
+	}
+	us := outTime.Microseconds()
+	return []string{
+		fmt.Sprintf("out_time_us=%d", us),
+		fmt.Sprintf("out_time_ms=%d", us),
+		"speed=1x",
+		"progress=" + state,
+	}
+}
+
+// ExitError 假进程非 0 退出时 Wait 返回的错误
+type ExitError struct {
+	Code   int    // 被信号终止为 -1
+	Signal string // 终止信号名
+}
+
+func (e *ExitError) Error() string {
+	if e.Signal != "" {
+		return "signal: " + e.Signal
+	}
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+func (e *ExitError) ExitCode() int { return e.Code }
+
+var errStopped = errors.New("stopped")
+
+// quitSignal stdin 读到 q，和交互式按 q 一样
+type quitSignal struct{}
+
+func (quitSignal) String() string { return "q" }
+func (quitSignal) Signal()        {}
+
+type process struct {
+	rec     *record
+	script  *Script
+	spec    proc.Spec
+	signals chan os.Signal
+
+	// 以下只在 run goroutine 里访问
+	paused   bool
+	deferred []os.Signal // 暂停期间收到的信号，恢复后处理
+	stopSig  os.Signal
+
+	mu     sync.Mutex
+	ctxErr error // ctx 结束导致的停止
+
+	done  chan struct{}
+	state *proc.State
+	err   error
+}
+
+func (p *process) Pid() int { return p.rec.call.Pid }
+
+func (p *process) Signal(sig os.Signal) error {
+	select {
+	case <-p.done:
+		return os.ErrProcessDone
+	default:
+	}
+	p.rec.mu.Lock()
+	p.rec.call.Signals = append(p.rec.call.Signals, sig)
+	p.rec.mu.Unlock()
+	p.send(sig)
+	return nil
+}
+
+func (p *process) send(sig os.Signal) {
+	select {
+	case p.signals <- sig:
+	case <-p.done:
+	}
+}
+
+func (p *process) Wait() (*proc.State, error) {
+	<-p.done
+	return p.state, p.err
+}
+
+// watch 模拟 exec.CommandContext：ctx 结束时调用 Spec.Cancel（默认杀进程），WaitDelay 后仍未退出就杀
+func (p *process) watch(ctx context.Context) {
+	select {
+	case <-p.done:
+		return
+	case <-ctx.Done():
+	}
+	p.mu.Lock()
+	p.ctxErr = ctx.Err()
+	p.mu.Unlock()
+	if p.spec.Cancel == nil || p.spec.Cancel(p) != nil {
+		_ = p.Signal(os.Kill)
+	}
+	if p.spec.WaitDelay <= 0 {
+		return
+	}
+	select {
+	case <-p.done:
+	case <-time.After(p.spec.WaitDelay):
+		_ = p.Signal(os.Kill)
+	}
+}
+
+func (p *process) run() {
+	defer close(p.done)
+	if p.spec.Stdin != nil {
+		go p.readStdin()
+	}
+
+	err := p.emit(p.spec.Stdout, p.script.stdout)
+	if err == nil {
+		err = p.emit(p.spec.Stderr, p.script.stderr)
+	}
+	if progress := p.progressWriter(); err == nil && progress != nil {
+		for _, line := range p.script.progress {
+			if err = p.emit(progress, line+"\n"); err != nil {
+				break
+			}
+			if strings.HasPrefix(line, "progress=") && p.script.interval > 0 {
+				if err = p.sleep(p.script.interval); err != nil {
+					break
+				}
+			}
+		}
+	}
+	if err == nil && p.script.hang {
+		err = p.sleep(-1)
+	}
+
+	code, sig := p.script.exit, ""
+	if err != nil {
+		code, sig = p.stopped()
+	}
+	p.state = &proc.State{ExitCode: code, Exited: sig == ""}
+	if code != 0 || sig != "" {
+		p.err = &ExitError{Code: code, Signal: sig}
+	} else if err != nil {
+		// 同 exec.Cmd：取消后即使正常退出，Wait 也返回 ctx 的错误
+		p.mu.Lock()
+		p.err = p.ctxErr
+		p.mu.Unlock()
+	}
+}
+
+// stopped 按停止方式给出退出码：q 正常收尾退出 0，SIGINT 收尾后 255，其余信号为被终止
+func (p *process) stopped() (int, string) {
+	switch p.stopSig {
+	case quitSignal{}:
+		return 0, ""
+	case os.Interrupt:
+		if p.spec.Stderr != nil {
+			_, _ = io.WriteString(p.spec.Stderr, "Exiting normally, received signal 2.\n")
+		}
+		return 255, ""
+	default:
+		return -1, p.stopSig.String()
+	}
+}
+
+// emit 写一段输出；暂停时阻塞，收到停止信号返回 errStopped
+func (p *process) emit(w io.Writer, s string) error {
+	if err := p.sleep(0); err != nil {
+		return err
+	}
+	if w != nil && s != "" {
+		_, _ = io.WriteString(w, s)
+	}
+	return nil
+}
+
+// sleep 等待 d（0 只处理已到的信号，<0 一直等），期间处理暂停/恢复；收到停止信号返回 errStopped
+func (p *process) sleep(d time.Duration) error {
+	var timeout <-chan time.Time
+	if d > 0 {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	for {
+		var sig os.Signal
+		switch {
+		case p.paused:
+			sig = <-p.signals
+		case d == 0:
+			select {
+			case sig = <-p.signals:
+			default:
+				return nil
+			}
+		default:
+			select {
+			case sig = <-p.signals:
+			case <-timeout:
+				return nil
+			}
+		}
+
+		switch {
+		case isStop(sig):
+			p.paused = true
+		case isCont(sig):
+			p.paused = false
+			if len(p.deferred) > 0 {
+				p.stopSig = p.deferred[0]
+				return errStopped
+			}
+		case p.paused && sig != os.Kill:
+			// 和真实进程一样：暂停中只有 kill 立即生效
+			p.deferred = append(p.deferred, sig)
+		default:
+			p.stopSig = sig
+			return errStopped
+		}
+	}
+}
+
+// progressWriter 按 -progress pipe:N 找进度输出位置
+func (p *process) progressWriter() io.Writer {
+	args := p.spec.Args
+	for i := 0; i+1 < len(args); i++ {
+		if args[i] != "-progress" {
+			continue
+		}
+		switch args[i+1] {
+		case "pipe:1", "-":
+			return p.spec.Stdout
+		case "pipe:2":
+			return p.spec.Stderr
+		case "pipe:3":
+			if len(p.spec.ExtraFiles) > 0 {
+				return p.spec.ExtraFiles[0]
+			}
+		}
+		return nil
+	}
+	// 没有 -progress 时进度行当作普通 stdout
+	return p.spec.Stdout
+}
+
+// readStdin 记录 stdin；stdin 不是媒体输入（pipe:0）时，读到 q 按退出处理
+func (p *process) readStdin() {
+	data := false
+	for i, a := range p.spec.Args {
+		if a == "pipe:0" || (a == "-" && i > 0 && p.spec.Args[i-1] == "-i") {
+			data = true
+		}
+	}
+	buf := make([]byte, 4096)
+	for {
+		n, err := p.spec.Stdin.Read(buf)
+		if n > 0 {
+			p.rec.mu.Lock()
+			p.rec.call.Stdin = append(p.rec.call.Stdin, buf[:n]...)
+			p.rec.mu.Unlock()
+			if !data && bytes.IndexByte(buf[:n], 'q') >= 0 {
+				p.send(quitSignal{})
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package testing_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/LingByte/LingConvert/media/ffmpeg"
+	fftest "github.com/LingByte/LingConvert/media/testing"
+)
+
+func transcode() *ffmpeg.FFmpegCommand {
+	return ffmpeg.NewFFmpegCommand().Input("in.mp4").VideoCodec("libx264").Output("out.mp4")
+}
+
+func TestProgressReplay(t *testing.T) {
+	fake := fftest.NewExecutor()
+	var lines []string
+	lines = append(lines, fftest.ProgressBlock(time.Second, false)...)
+	lines = append(lines, fftest.ProgressBlock(2*time.Second, true)...)
+	fake.On("-i in.mp4").Progress(lines...)
+
+	tool := &ffmpeg.FFmpegTool{Executor: fake}
+	var got []time.Duration
+	last, err := tool.RunWithProgress(context.Background(), transcode(), func(p ffmpeg.FFmpegProgress) error {
+		// 每行进度都会回调一次，只记录变化
+		if len(got) == 0 || got[len(got)-1] != p.OutTime {
+			got = append(got, p.OutTime)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != time.Second || got[1] != 2*time.Second {
+		t.Errorf("progress = %v, want [1s 2s]", got)
+	}
+	if last.OutTime != 2*time.Second || last.Speed != 1 {
+		t.Errorf("last = %+v", last)
+	}
+}
+
+func TestExitClassified(t *testing.T) {
+	fake := fftest.NewExecutor()
+	fake.On("-i in.mp4").Stderr("in.mp4: No such file or directory\n").Exit(1)
+
+	tool := &ffmpeg.FFmpegTool{Executor: fake}
+	res, err := tool.RunWithResult(context.Background(), transcode(), ffmpeg.RunOptions{})
+	if !errors.Is(err, ffmpeg.ErrInputNotFound) {
+		t.Fatalf("err = %v, want ErrInputNotFound", err)
+	}
+	var fe *ffmpeg.Error
+	if !errors.As(err, &fe) || fe.ExitCode != 1 {
+		t.Errorf("err = %#v, want *ffmpeg.Error with exit code 1", err)
+	}
+	if res == nil || res.ExitCode != 1 {
+		t.Errorf("result = %+v, want exit code 1", res)
+	}
+}
+
+func TestGracefulStop(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SIGINT is not supported on windows")
+	}
+	tests := []struct {
+		name string
+		mode ffmpeg.StopMode
+		exit int
+	}{
+		{"quit", ffmpeg.StopQuit, 0},
+		{"interrupt", ffmpeg.StopInterrupt, 255},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := fftest.NewExecutor()
+			fake.On("-i in.mp4").Progress(fftest.ProgressBlock(time.Second, false)...).Hang()
+
+			tool := &ffmpeg.FFmpegTool{Executor: fake, StopMode: tt.mode, StopGrace: time.Second}
+			ctx, cancel := context.WithCancel(context.Background())
+			started := make(chan struct{})
+			var once sync.Once
+			job, err := tool.Start(ctx, transcode(), ffmpeg.RunOptions{OnProgress: func(ffmpeg.FFmpegProgress) error {
+				once.Do(func() { close(started) })
+				return nil
+			}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			<-started
+			cancel()
+
+			res, err := job.Result()
+			if !errors.Is(err, ffmpeg.ErrStoppedEarly) {
+				t.Fatalf("err = %v, want ErrStoppedEarly", err)
+			}
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("err = %v, want it to wrap context.Canceled", err)
+			}
+			if res.ExitCode != tt.exit {
+				t.Errorf("exit code = %d, want %d", res.ExitCode, tt.exit)
+			}
+
+			calls := fake.Calls()
+			call := calls[len(calls)-1]
+			switch tt.mode {
+			case ffmpeg.StopQuit:
+				if !bytes.Contains(call.Stdin, []byte("q")) {
+					t.Errorf("stdin = %q, want q", call.Stdin)
+				}
+			case ffmpeg.StopInterrupt:
+				if len(call.Signals) == 0 || call.Signals[0] != os.Interrupt {
+					t.Errorf("signals = %v, want interrupt", call.Signals)
+				}
+			}
+		})
+	}
+}
+
+func TestPauseExcludedFromTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("pause is not supported on windows")
+	}
+	const (
+		interval = 100 * time.Millisecond
+		timeout  = 400 * time.Millisecond
+		paused   = 600 * time.Millisecond
+	)
+	fake := fftest.NewExecutor()
+	var lines []string
+	lines = append(lines, fftest.ProgressBlock(time.Second, false)...)
+	lines = append(lines, fftest.ProgressBlock(2*time.Second, true)...)
+	fake.On("-i in.mp4").Progress(lines...).Interval(interval)
+
+	// 实际运行约 2*interval，加上暂停后墙钟时间超过 Timeout
+	tool := &ffmpeg.FFmpegTool{Executor: fake, Timeout: timeout}
+	first := make(chan struct{})
+	var once sync.Once
+	job, err := tool.Start(context.Background(), transcode(), ffmpeg.RunOptions{OnProgress: func(p ffmpeg.FFmpegProgress) error {
+		if !p.Paused {
+			once.Do(func() { close(first) })
+		}
+		return nil
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-first
+	if err := job.Pause(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(paused)
+	if err := job.Resume(); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := job.Result()
+	if err != nil {
+		t.Fatalf("err = %v, want success: pause must not count towards Timeout", err)
+	}
+	if res.WallTime < timeout {
+		t.Errorf("wall time = %s, want more than the %s timeout", res.WallTime, timeout)
+	}
+	if res.Progress.OutTime != 2*time.Second {
+		t.Errorf("last progress = %+v", res.Progress)
+	}
+}
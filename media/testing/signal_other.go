@@ -0,0 +1,8 @@
+//go:build !unix
+
+package testing
+
+import "os"
+
+func isStop(sig os.Signal) bool { return false }
+func isCont(sig os.Signal) bool { return false }
@@ -0,0 +1,11 @@
+//go:build unix
+
+package testing
+
+import (
+	"os"
+	"syscall"
+)
+
+func isStop(sig os.Signal) bool { return sig == syscall.SIGSTOP }
+func isCont(sig os.Signal) bool { return sig == syscall.SIGCONT }